@@ -1,3 +1,5 @@
 module srcrd
 
 go 1.16
+
+require golang.org/x/net v0.33.0
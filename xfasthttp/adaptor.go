@@ -0,0 +1,154 @@
+package xfasthttp
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+// NewFastHTTPHandlerFunc wraps net/http handler func to xfasthttp
+// request handler, so it can be passed to xfasthttp server.
+func NewFastHTTPHandlerFunc(h http.HandlerFunc) RequestHandler {
+	return NewFastHTTPHandler(h)
+}
+
+// NewFastHTTPHandler wraps net/http handler to xfasthttp request handler,
+// so it can be passed to xfasthttp server.
+//
+// The http.Request and http.ResponseWriter passed to h are pooled, h must not
+// use them after it returns. The response is buffered in ctx.Response and
+// written once h returns, so Flush is a no-op and Hijack is not supported.
+func NewFastHTTPHandler(h http.Handler) RequestHandler {
+	return func(ctx *RequestCtx) {
+		r := acquireHTTPRequest()
+		defer releaseHTTPRequest(r)
+		if err := ConvertRequest(ctx, r); err != nil {
+			ctx.Response.SetStatusCode(http.StatusBadRequest)
+			ctx.Response.WriteString("Bad Request")
+			return
+		}
+
+		w := acquireResponseWriter(ctx)
+		defer releaseResponseWriter(w)
+		h.ServeHTTP(w, r)
+		ctx.Response.SetStatusCode(w.StatusCode())
+	}
+}
+
+// ConvertRequest converts a xfasthttp.Request to an http.Request.
+// The header map is shared, the body is read from ctx.Request.Body().
+func ConvertRequest(ctx *RequestCtx, r *http.Request) error {
+	req := &ctx.Request
+	u, err := url.ParseRequestURI(req.RequestURI)
+	if err != nil {
+		return err
+	}
+
+	r.Method = req.Method
+	r.Proto = req.Proto
+	r.ProtoMajor = req.ProtoMajor
+	r.ProtoMinor = req.ProtoMinor
+	r.RequestURI = req.RequestURI
+	r.URL = u
+	r.Host = req.Host
+	if r.Host == "" {
+		r.Host = u.Host
+	}
+	r.RemoteAddr = ctx.RemoteAddr().String()
+	r.Header = req.Header
+	if r.Header == nil {
+		r.Header = make(http.Header)
+	}
+
+	body := req.Body()
+	r.ContentLength = int64(len(body))
+	if len(body) == 0 {
+		r.Body = http.NoBody
+	} else {
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+	return nil
+}
+
+var httpRequestPool sync.Pool
+
+func acquireHTTPRequest() *http.Request {
+	if v := httpRequestPool.Get(); v != nil {
+		return v.(*http.Request)
+	}
+	return &http.Request{}
+}
+
+func releaseHTTPRequest(r *http.Request) {
+	*r = http.Request{}
+	httpRequestPool.Put(r)
+}
+
+// netHTTPResponseWriter writes the http.Handler's response into ctx.Response.
+type netHTTPResponseWriter struct {
+	statusCode int
+	ctx        *RequestCtx
+}
+
+var (
+	_ http.ResponseWriter = &netHTTPResponseWriter{}
+	_ http.Flusher        = &netHTTPResponseWriter{}
+	_ http.Hijacker       = &netHTTPResponseWriter{}
+)
+
+var responseWriterPool = sync.Pool{
+	New: func() interface{} {
+		return &netHTTPResponseWriter{}
+	},
+}
+
+func acquireResponseWriter(ctx *RequestCtx) *netHTTPResponseWriter {
+	w := responseWriterPool.Get().(*netHTTPResponseWriter)
+	w.ctx = ctx
+	return w
+}
+
+func releaseResponseWriter(w *netHTTPResponseWriter) {
+	w.statusCode = 0
+	w.ctx = nil
+	responseWriterPool.Put(w)
+}
+
+func (w *netHTTPResponseWriter) StatusCode() int {
+	if w.statusCode == 0 {
+		return http.StatusOK
+	}
+	return w.statusCode
+}
+
+func (w *netHTTPResponseWriter) Header() http.Header {
+	return w.ctx.Response.Header
+}
+
+func (w *netHTTPResponseWriter) WriteHeader(statusCode int) {
+	if w.statusCode == 0 {
+		w.statusCode = statusCode
+	}
+}
+
+func (w *netHTTPResponseWriter) Write(p []byte) (int, error) {
+	if w.statusCode == 0 {
+		w.statusCode = http.StatusOK
+	}
+	return w.ctx.Response.Write(p)
+}
+
+// Flush is a no-op, the response is written once the handler returns.
+func (w *netHTTPResponseWriter) Flush() {}
+
+// Hijack is not supported, the connection is owned by the xfasthttp server.
+func (w *netHTTPResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return nil, nil, errHijackNotSupported
+}
+
+var errHijackNotSupported = errors.New("xfasthttp: Hijack is not supported by NewFastHTTPHandler")
@@ -0,0 +1,280 @@
+package xfasthttp
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultMaxRequestBodySize is the maximum request body size the server
+	// reads by default.
+	DefaultMaxRequestBodySize = 4 * 1024 * 1024
+
+	defaultReadBufferSize  = 4096
+	defaultWriteBufferSize = 4096
+
+	defaultServerName  = "xfasthttp"
+	defaultContentType = "text/plain; charset=utf-8"
+)
+
+var (
+	// ErrGetOnly is returned when the server is configured to accept only
+	// GET requests and a non-GET request is received.
+	ErrGetOnly = errors.New("non-GET request received")
+
+	// ErrBodyTooLarge is returned if the request body exceeds MaxRequestBodySize.
+	ErrBodyTooLarge = errors.New("body size exceeds the given limit")
+
+	errMalformedRequestLine = errors.New("malformed HTTP request line")
+	errMalformedTrailer     = errors.New("malformed chunked body trailer")
+
+	errUnsupportedTransferEncoding = errors.New("unsupported Transfer-Encoding")
+	errAmbiguousBodyLength         = errors.New("ambiguous request body length")
+)
+
+// Request represents HTTP request.
+//
+// Request instance MUST NOT be used from concurrently running goroutines.
+type Request struct {
+	noCopy noCopy //nolint:unused,structcheck
+
+	Method     string
+	RequestURI string
+	Proto      string
+	ProtoMajor int
+	ProtoMinor int
+	Host       string
+
+	// Header is allocated for every request, so it may outlive the request.
+	Header http.Header
+
+	body []byte
+}
+
+// Body returns request body.
+func (req *Request) Body() []byte {
+	return req.body
+}
+
+// ConnectionClose returns true if the connection must be closed after the response.
+func (req *Request) ConnectionClose() bool {
+	conn := req.Header.Get("Connection")
+	if req.ProtoMajor == 1 && req.ProtoMinor == 0 {
+		// HTTP/1.0 默认短连接
+		return !strings.EqualFold(conn, "keep-alive")
+	}
+	return strings.EqualFold(conn, "close")
+}
+
+// Reset clears request contents.
+func (req *Request) Reset() {
+	req.Method = ""
+	req.RequestURI = ""
+	req.Proto = ""
+	req.ProtoMajor = 0
+	req.ProtoMinor = 0
+	req.Host = ""
+	req.Header = nil
+	req.body = nil
+}
+
+// readLimitBody reads request from the given r, limiting the body size.
+func (req *Request) readLimitBody(r *bufio.Reader, maxBodySize int, getOnly bool) error {
+	tp := textproto.NewReader(r)
+	line, err := tp.ReadLine()
+	if err != nil {
+		return err
+	}
+
+	// GET /index.html HTTP/1.1
+	method, rest := splitOnSpace(line)
+	requestURI, proto := splitOnSpace(rest)
+	if method == "" || requestURI == "" || proto == "" {
+		return errMalformedRequestLine
+	}
+	major, minor, ok := http.ParseHTTPVersion(proto)
+	if !ok {
+		return errMalformedRequestLine
+	}
+	req.Method, req.RequestURI, req.Proto = method, requestURI, proto
+	req.ProtoMajor, req.ProtoMinor = major, minor
+
+	if getOnly && method != http.MethodGet {
+		return ErrGetOnly
+	}
+
+	header, err := tp.ReadMIMEHeader()
+	if err != nil {
+		return err
+	}
+	req.Header = http.Header(header)
+	req.Host = req.Header.Get("Host")
+	req.Header.Del("Host")
+
+	return req.readBody(r, maxBodySize)
+}
+
+func (req *Request) readBody(r *bufio.Reader, maxBodySize int) error {
+	var body io.Reader
+	// 只支持单独的chunked, 其他编码和Content-Length并存时前面的代理可能按
+	// 另一种方式确定body的长度, 同一连接上的请求会错位(request smuggling)
+	te := req.Header["Transfer-Encoding"]
+	chunked := len(te) > 0
+	if chunked && (len(te) > 1 || !strings.EqualFold(strings.TrimSpace(te[0]), "chunked")) {
+		return errUnsupportedTransferEncoding
+	}
+	if cl := req.Header["Content-Length"]; (chunked && len(cl) > 0) || len(cl) > 1 {
+		return errAmbiguousBodyLength
+	}
+	if chunked {
+		body = httputil.NewChunkedReader(r)
+	} else if cl := req.Header.Get("Content-Length"); cl != "" {
+		n, err := strconv.ParseInt(cl, 10, 64)
+		if err != nil || n < 0 {
+			return errors.New("invalid Content-Length " + strconv.Quote(cl))
+		}
+		if n > int64(maxBodySize) {
+			return ErrBodyTooLarge
+		}
+		body = io.LimitReader(r, n)
+	} else {
+		return nil
+	}
+
+	// 多读一个字节判断是否超出限制
+	b, err := io.ReadAll(io.LimitReader(body, int64(maxBodySize)+1))
+	if err != nil {
+		return err
+	}
+	if len(b) > maxBodySize {
+		return ErrBodyTooLarge
+	}
+	if chunked {
+		// ChunkedReader读到0长度的chunk就结束了, 后面的trailer和空行要读掉,
+		// 否则keep-alive连接上的下一个请求会从这里开始解析. trailer不使用
+		if _, err := textproto.NewReader(r).ReadMIMEHeader(); err != nil {
+			return errMalformedTrailer
+		}
+	}
+	req.body = b
+	return nil
+}
+
+func splitOnSpace(s string) (string, string) {
+	if i := strings.IndexByte(s, ' '); i >= 0 {
+		return s[:i], s[i+1:]
+	}
+	return s, ""
+}
+
+// Response represents HTTP response.
+//
+// Response instance MUST NOT be used from concurrently running goroutines.
+type Response struct {
+	noCopy noCopy //nolint:unused,structcheck
+
+	Header http.Header
+
+	statusCode      int
+	body            bytes.Buffer
+	connectionClose bool
+}
+
+// StatusCode returns response status code.
+func (resp *Response) StatusCode() int {
+	if resp.statusCode == 0 {
+		return http.StatusOK
+	}
+	return resp.statusCode
+}
+
+// SetStatusCode sets response status code.
+func (resp *Response) SetStatusCode(statusCode int) {
+	resp.statusCode = statusCode
+}
+
+// SetConnectionClose sets 'Connection: close' header.
+func (resp *Response) SetConnectionClose() {
+	resp.connectionClose = true
+}
+
+// Write appends p to response body.
+func (resp *Response) Write(p []byte) (int, error) {
+	return resp.body.Write(p)
+}
+
+// WriteString appends s to response body.
+func (resp *Response) WriteString(s string) (int, error) {
+	return resp.body.WriteString(s)
+}
+
+// Body returns response body.
+func (resp *Response) Body() []byte {
+	return resp.body.Bytes()
+}
+
+// Reset clears response contents.
+func (resp *Response) Reset() {
+	resp.Header = make(http.Header)
+	resp.statusCode = 0
+	resp.body.Reset()
+	resp.connectionClose = false
+}
+
+// write writes response with the default headers to w.
+// The body is omitted for HEAD requests, see skipBody.
+func (resp *Response) write(w *bufio.Writer, s *Server, now time.Time, skipBody bool) error {
+	code := resp.StatusCode()
+	w.WriteString("HTTP/1.1 " + strconv.Itoa(code) + " " + http.StatusText(code) + "\r\n")
+
+	h := resp.Header
+	if !s.NoDefaultServerHeader && h.Get("Server") == "" {
+		h.Set("Server", s.getServerName())
+	}
+	if !s.NoDefaultDate && h.Get("Date") == "" {
+		h.Set("Date", now.UTC().Format(http.TimeFormat))
+	}
+	if !s.NoDefaultContentType && h.Get("Content-Type") == "" && bodyAllowedForStatus(code) {
+		h.Set("Content-Type", defaultContentType)
+	}
+	// body已经完整缓存, 统一使用Content-Length
+	h.Del("Transfer-Encoding")
+	if bodyAllowedForStatus(code) {
+		h.Set("Content-Length", strconv.Itoa(resp.body.Len()))
+	} else {
+		h.Del("Content-Length")
+	}
+	if resp.connectionClose {
+		h.Set("Connection", "close")
+	}
+	if err := h.Write(w); err != nil {
+		return err
+	}
+	w.WriteString("\r\n")
+	if bodyAllowedForStatus(code) && !skipBody {
+		w.Write(resp.body.Bytes())
+	}
+	return w.Flush()
+}
+
+// bodyAllowedForStatus reports whether a given response status code
+// permits a body. See RFC 7230, section 3.3.
+func bodyAllowedForStatus(status int) bool {
+	switch {
+	case status >= 100 && status <= 199:
+		return false
+	case status == http.StatusNoContent:
+		return false
+	case status == http.StatusNotModified:
+		return false
+	}
+	return true
+}
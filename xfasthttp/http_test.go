@@ -0,0 +1,73 @@
+package xfasthttp
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+)
+
+func TestReadLimitBodyKeepAlive(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"content length", "Content-Length: 5\r\n\r\nhello", "hello"},
+		{"chunked", "Transfer-Encoding: chunked\r\n\r\n2\r\nhe\r\n3\r\nllo\r\n0\r\n\r\n", "hello"},
+		{"chunked with trailer", "Transfer-Encoding: chunked\r\n\r\n5\r\nhello\r\n0\r\nX-Sum: 1\r\n\r\n", "hello"},
+		{"chunked empty", "Transfer-Encoding: chunked\r\n\r\n0\r\n\r\n", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 同一连接上的第二个请求要能正常解析
+			raw := "POST /a HTTP/1.1\r\nHost: x\r\n" + tt.body + "GET /b HTTP/1.1\r\nHost: x\r\n\r\n"
+			r := bufio.NewReader(strings.NewReader(raw))
+
+			var req Request
+			if err := req.readLimitBody(r, DefaultMaxRequestBodySize, false); err != nil {
+				t.Fatalf("first request: %v", err)
+			}
+			if got := string(req.Body()); got != tt.want {
+				t.Errorf("body = %q, want %q", got, tt.want)
+			}
+
+			req.Reset()
+			if err := req.readLimitBody(r, DefaultMaxRequestBodySize, false); err != nil {
+				t.Fatalf("second request: %v", err)
+			}
+			if req.Method != "GET" || req.RequestURI != "/b" {
+				t.Errorf("second request = %s %s, want GET /b", req.Method, req.RequestURI)
+			}
+		})
+	}
+}
+
+func TestReadLimitBodyErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		maxBody int
+		wantErr error
+	}{
+		{"malformed trailer", "POST / HTTP/1.1\r\nTransfer-Encoding: chunked\r\n\r\n1\r\na\r\n0\r\nbad trailer\r\n\r\n", 10, errMalformedTrailer},
+		{"unterminated trailer", "POST / HTTP/1.1\r\nTransfer-Encoding: chunked\r\n\r\n1\r\na\r\n0\r\n", 10, errMalformedTrailer},
+		{"chunked too large", "POST / HTTP/1.1\r\nTransfer-Encoding: chunked\r\n\r\nb\r\nhello world\r\n0\r\n\r\n", 10, ErrBodyTooLarge},
+		{"content length too large", "POST / HTTP/1.1\r\nContent-Length: 11\r\n\r\nhello world", 10, ErrBodyTooLarge},
+		{"malformed request line", "POST /\r\n\r\n", 10, errMalformedRequestLine},
+		{"get only", "POST / HTTP/1.1\r\n\r\n", 10, ErrGetOnly},
+		{"gzip chunked", "POST / HTTP/1.1\r\nTransfer-Encoding: gzip, chunked\r\n\r\n0\r\n\r\n", 10, errUnsupportedTransferEncoding},
+		{"identity", "POST / HTTP/1.1\r\nTransfer-Encoding: identity\r\n\r\n", 10, errUnsupportedTransferEncoding},
+		{"repeated transfer encoding", "POST / HTTP/1.1\r\nTransfer-Encoding: chunked\r\nTransfer-Encoding: chunked\r\n\r\n0\r\n\r\n", 10, errUnsupportedTransferEncoding},
+		{"chunked with content length", "POST / HTTP/1.1\r\nTransfer-Encoding: chunked\r\nContent-Length: 3\r\n\r\n0\r\n\r\n", 10, errAmbiguousBodyLength},
+		{"repeated content length", "POST / HTTP/1.1\r\nContent-Length: 1\r\nContent-Length: 2\r\n\r\nab", 10, errAmbiguousBodyLength},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req Request
+			err := req.readLimitBody(bufio.NewReader(strings.NewReader(tt.raw)), tt.maxBody, tt.wantErr == ErrGetOnly)
+			if err != tt.wantErr {
+				t.Errorf("readLimitBody error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -1,9 +1,12 @@
 package xfasthttp
 
 import (
+	"bufio"
 	"crypto/tls"
+	"errors"
 	"io"
 	"net"
+	"net/http"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -11,10 +14,10 @@ import (
 
 // RequestHandler must process incoming requests.
 //
-// RequestHandler must call ctx.TimeoutError() before returning
-// if it keeps references to ctx and/or its' members after the return.
-// Consider wrapping RequestHandler into TimeoutHandler if response time
-// must be limited.
+// RequestHandler must not keep references to ctx and/or its' members
+// after the return, the RequestCtx is reused by the next request.
+type RequestHandler func(ctx *RequestCtx)
+
 // ServeHandler must process tls.Config.NextProto negotiated requests.
 type ServeHandler func(c net.Conn) error
 
@@ -25,7 +28,7 @@ type Server struct {
 	//
 	// Take into account that no `panic` recovery is done by `fasthttp` (thus any `panic` will take down the entire server).
 	// Instead the user should use `recover` to handle these situations.
-	// Handler RequestHandler
+	Handler RequestHandler
 
 	// ErrorHandler for returning a response in case of an error while receiving or parsing the request.
 	//
@@ -374,9 +377,11 @@ func (s *Server) Serve(ln net.Listener) error {
 	}
 }
 
-func ListenAndServe(addr string) error {
+// ListenAndServe serves HTTP requests from the given TCP addr
+// using the given handler.
+func ListenAndServe(addr string, handler RequestHandler) error {
 	s := &Server{
-		// Handler: handler,
+		Handler: handler,
 	}
 	return s.ListenAndServe(addr)
 }
@@ -386,11 +391,226 @@ func (s *Server) serveConnCleanup() {
 	atomic.AddUint32(&s.concurrency, ^uint32(0))
 }
 
+// RequestCtx contains incoming request and manages outgoing response.
+//
+// It is forbidden copying RequestCtx instances.
+//
+// RequestHandler should avoid holding references to incoming RequestCtx and/or
+// its' members after the return.
+type RequestCtx struct {
+	noCopy noCopy //nolint:unused,structcheck
+
+	// Incoming request.
+	Request Request
+
+	// Outgoing response.
+	Response Response
+
+	connRequestNum uint64
+	time           time.Time
+
+	s *Server
+	c net.Conn
+}
+
+// RemoteAddr returns client address for the given request.
+func (ctx *RequestCtx) RemoteAddr() net.Addr {
+	if ctx.c == nil {
+		return zeroTCPAddr
+	}
+	addr := ctx.c.RemoteAddr()
+	if addr == nil {
+		return zeroTCPAddr
+	}
+	return addr
+}
+
+// LocalAddr returns server address for the given request.
+func (ctx *RequestCtx) LocalAddr() net.Addr {
+	if ctx.c == nil {
+		return zeroTCPAddr
+	}
+	addr := ctx.c.LocalAddr()
+	if addr == nil {
+		return zeroTCPAddr
+	}
+	return addr
+}
+
+// ConnRequestNum returns request sequence number
+// for the current connection.
+//
+// Sequence starts with 1.
+func (ctx *RequestCtx) ConnRequestNum() uint64 {
+	return ctx.connRequestNum
+}
+
+// Time returns RequestHandler call time.
+func (ctx *RequestCtx) Time() time.Time {
+	return ctx.time
+}
+
+var zeroTCPAddr = &net.TCPAddr{
+	IP: net.IPv4zero,
+}
+
+func (s *Server) getServerName() string {
+	if s.Name == "" {
+		return defaultServerName
+	}
+	return s.Name
+}
+
+func (s *Server) acquireCtx(c net.Conn) *RequestCtx {
+	v := s.ctxPool.Get()
+	var ctx *RequestCtx
+	if v == nil {
+		ctx = &RequestCtx{s: s}
+	} else {
+		ctx = v.(*RequestCtx)
+	}
+	ctx.c = c
+	return ctx
+}
+
+func (s *Server) releaseCtx(ctx *RequestCtx) {
+	ctx.c = nil
+	ctx.Request.Reset()
+	ctx.Response.Reset()
+	s.ctxPool.Put(ctx)
+}
+
+func (s *Server) acquireReader(c net.Conn) *bufio.Reader {
+	v := s.readerPool.Get()
+	if v == nil {
+		n := s.ReadBufferSize
+		if n <= 0 {
+			n = defaultReadBufferSize
+		}
+		return bufio.NewReaderSize(c, n)
+	}
+	r := v.(*bufio.Reader)
+	r.Reset(c)
+	return r
+}
+
+func (s *Server) releaseReader(r *bufio.Reader) {
+	s.readerPool.Put(r)
+}
+
+func (s *Server) acquireWriter(c net.Conn) *bufio.Writer {
+	v := s.writerPool.Get()
+	if v == nil {
+		n := s.WriteBufferSize
+		if n <= 0 {
+			n = defaultWriteBufferSize
+		}
+		return bufio.NewWriterSize(c, n)
+	}
+	w := v.(*bufio.Writer)
+	w.Reset(c)
+	return w
+}
+
+func (s *Server) releaseWriter(w *bufio.Writer) {
+	s.writerPool.Put(w)
+}
+
+// 每个连接上循环: 读请求 -> 调用Handler -> 写响应, 直到连接需要关闭
 func (s *Server) serveConn(c net.Conn) (err error) {
 	defer s.serveConnCleanup()
 	atomic.AddUint32(&s.concurrency, 1)
+	defer c.Close()
+
+	maxRequestBodySize := s.MaxRequestBodySize
+	if maxRequestBodySize <= 0 {
+		maxRequestBodySize = DefaultMaxRequestBodySize
+	}
+
+	br := s.acquireReader(c)
+	bw := s.acquireWriter(c)
+	ctx := s.acquireCtx(c)
+	defer func() {
+		s.releaseReader(br)
+		s.releaseWriter(bw)
+		s.releaseCtx(ctx)
+	}()
+
+	var connRequestNum uint64
+	for {
+		connRequestNum++
+		ctx.Request.Reset()
+		ctx.Response.Reset()
+
+		// 第一个请求之后, 等待下一个请求的时间由IdleTimeout控制
+		if connRequestNum > 1 && s.IdleTimeout > 0 {
+			c.SetReadDeadline(time.Now().Add(s.IdleTimeout))
+			if _, err = br.Peek(1); err != nil {
+				break
+			}
+		}
+		if s.ReadTimeout > 0 {
+			c.SetReadDeadline(time.Now().Add(s.ReadTimeout))
+		}
 
-	c.Write([]byte("serveConn"))
-	c.Close()
+		if err = ctx.Request.readLimitBody(br, maxRequestBodySize, s.GetOnly); err != nil {
+			if err == io.EOF || connRequestNum > 1 && isTimeout(err) {
+				err = nil
+				break
+			}
+			s.writeErrorResponse(bw, ctx, err)
+			break
+		}
+
+		ctx.connRequestNum = connRequestNum
+		ctx.time = time.Now()
+		if s.Handler != nil {
+			s.Handler(ctx)
+		} else {
+			ctx.Response.SetStatusCode(http.StatusNotFound)
+		}
+
+		connectionClose := s.DisableKeepalive || ctx.Request.ConnectionClose() || ctx.Response.connectionClose ||
+			(s.MaxRequestsPerConn > 0 && connRequestNum >= uint64(s.MaxRequestsPerConn)) ||
+			atomic.LoadInt32(&s.stop) == 1
+		if connectionClose {
+			ctx.Response.SetConnectionClose()
+		}
+
+		if s.WriteTimeout > 0 {
+			c.SetWriteDeadline(time.Now().Add(s.WriteTimeout))
+		}
+		if err = ctx.Response.write(bw, s, ctx.time, ctx.Request.Method == http.MethodHead); err != nil {
+			break
+		}
+		if connectionClose {
+			break
+		}
+	}
 	return
 }
+
+// writeErrorResponse 请求解析失败时返回错误响应并关闭连接
+func (s *Server) writeErrorResponse(bw *bufio.Writer, ctx *RequestCtx, err error) {
+	ctx.Response.Reset()
+	switch {
+	case err == ErrBodyTooLarge:
+		ctx.Response.SetStatusCode(http.StatusRequestEntityTooLarge)
+	case err == ErrGetOnly:
+		ctx.Response.SetStatusCode(http.StatusMethodNotAllowed)
+	case err == errUnsupportedTransferEncoding:
+		ctx.Response.SetStatusCode(http.StatusNotImplemented)
+	case isTimeout(err):
+		ctx.Response.SetStatusCode(http.StatusRequestTimeout)
+	default:
+		ctx.Response.SetStatusCode(http.StatusBadRequest)
+	}
+	ctx.Response.WriteString("Error when parsing request")
+	ctx.Response.SetConnectionClose()
+	ctx.Response.write(bw, s, time.Now(), false)
+}
+
+func isTimeout(err error) bool {
+	var ne net.Error
+	return errors.As(err, &ne) && ne.Timeout()
+}
@@ -0,0 +1,257 @@
+package xgin
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// apacheTimeFormat is the %t format of the Apache access logs.
+const apacheTimeFormat = "02/Jan/2006:15:04:05 -0700"
+
+// AccessLog returns a Logger middleware writing access log lines formatted by f,
+// ie. CombinedLogFormatter, to out. Writing through an AsyncWriter, a slow
+// file never stalls the requests. Close out on shutdown to write out the
+// queued lines.
+//
+//	w := xgin.NewAsyncWriter(file, 0)
+//	defer w.Close()
+//	r.Use(xgin.AccessLog(xgin.CombinedLogFormatter, w))
+func AccessLog(f LogFormatter, out *AsyncWriter) HandlerFunc {
+	assert1(out != nil, "access log writer must not be nil")
+	return LoggerWithFormatter(f, out)
+}
+
+// CommonLogFormatter formats the Apache Common Log Format:
+//
+//	127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.0" 200 2326
+func CommonLogFormatter(param LogFormatterParams) string {
+	var b strings.Builder
+	writeCommonLog(&b, param)
+	b.WriteByte('\n')
+	return b.String()
+}
+
+// CombinedLogFormatter formats the Apache Combined Log Format, the common
+// format followed by the Referer and User-Agent headers.
+func CombinedLogFormatter(param LogFormatterParams) string {
+	var b strings.Builder
+	writeCommonLog(&b, param)
+	req := param.Request.Request
+	b.WriteString(` "`)
+	b.WriteString(apacheEscape(req.Referer()))
+	b.WriteString(`" "`)
+	b.WriteString(apacheEscape(req.UserAgent()))
+	b.WriteString("\"\n")
+	return b.String()
+}
+
+func writeCommonLog(b *strings.Builder, param LogFormatterParams) {
+	b.WriteString(orDash(param.ClientIP))
+	b.WriteString(" - ")
+	b.WriteString(orDash(apacheEscape(logUser(param.Request))))
+	b.WriteString(" [")
+	b.WriteString(param.TimeStamp.Add(-param.Latency).Format(apacheTimeFormat))
+	b.WriteString(`] "`)
+	b.WriteString(apacheEscape(param.Method))
+	b.WriteByte(' ')
+	b.WriteString(apacheEscape(param.Path))
+	b.WriteByte(' ')
+	b.WriteString(apacheEscape(param.Request.Request.Proto))
+	b.WriteString(`" `)
+	b.WriteString(strconv.Itoa(param.StatusCode))
+	b.WriteByte(' ')
+	if param.BodySize > 0 {
+		b.WriteString(strconv.Itoa(param.BodySize))
+	} else {
+		b.WriteByte('-')
+	}
+}
+
+type jsonLogLine struct {
+	Time      string  `json:"time"`
+	ClientIP  string  `json:"client_ip"`
+	User      string  `json:"user,omitempty"`
+	Method    string  `json:"method"`
+	URI       string  `json:"uri"`
+	Route     string  `json:"route,omitempty"`
+	Proto     string  `json:"proto"`
+	Status    int     `json:"status"`
+	Size      int     `json:"size"`
+	LatencyMS float64 `json:"latency_ms"`
+	Referer   string  `json:"referer,omitempty"`
+	UserAgent string  `json:"user_agent,omitempty"`
+	RequestID string  `json:"request_id,omitempty"`
+	TraceID   string  `json:"trace_id,omitempty"`
+	Errors    string  `json:"errors,omitempty"`
+}
+
+// JSONLogFormatter formats one JSON object per line, with the route (FullPath)
+// and the latency in milliseconds.
+func JSONLogFormatter(param LogFormatterParams) string {
+	c := param.Request
+	size := param.BodySize
+	if size < 0 {
+		size = 0
+	}
+	line := jsonLogLine{
+		Time:      param.TimeStamp.Add(-param.Latency).Format(time.RFC3339Nano),
+		ClientIP:  param.ClientIP,
+		User:      logUser(c),
+		Method:    param.Method,
+		URI:       param.Path,
+		Route:     param.FullPath,
+		Proto:     c.Request.Proto,
+		Status:    param.StatusCode,
+		Size:      size,
+		LatencyMS: float64(param.Latency) / float64(time.Millisecond),
+		Referer:   c.Request.Referer(),
+		UserAgent: c.Request.UserAgent(),
+		RequestID: param.RequestID,
+		TraceID:   param.TraceID,
+		Errors:    strings.TrimSuffix(c.Errors.ByType(ErrorTypePrivate).String(), "\n"),
+	}
+	b, _ := json.Marshal(line)
+	return string(b) + "\n"
+}
+
+// logUser returns the user set by BasicAuth or BearerAuth if it is a string.
+func logUser(c *Context) string {
+	if v, ok := c.Get(AuthUserKey); ok {
+		if user, ok := v.(string); ok {
+			return user
+		}
+	}
+	return ""
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// apacheEscape escapes quotes, backslashes and control characters like Apache does.
+func apacheEscape(s string) string {
+	i := 0
+	for ; i < len(s); i++ {
+		if c := s[i]; c == '"' || c == '\\' || c < 0x20 || c == 0x7f {
+			break
+		}
+	}
+	if i == len(s) {
+		return s
+	}
+
+	var b strings.Builder
+	b.WriteString(s[:i])
+	for ; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c < 0x20 || c == 0x7f:
+			b.WriteString(`\x`)
+			b.WriteByte("0123456789abcdef"[c>>4])
+			b.WriteByte("0123456789abcdef"[c&0xf])
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// ErrAsyncWriterClosed is returned by Write after Close.
+var ErrAsyncWriterClosed = errors.New("xgin: async writer closed")
+
+// AsyncWriter is an io.Writer that queues the writes and writes them out
+// from its own goroutine through a buffer, flushed when the queue is empty.
+// Write never blocks: when the queue is full the data is dropped and counted.
+type AsyncWriter struct {
+	dropped uint64 // 放在最前面, 保证32位平台上原子操作8字节对齐
+	out     io.Writer
+	ch      chan []byte
+	done    chan struct{}
+	mu      sync.RWMutex
+	closed  bool
+	err     error // 最近一次写out的错误, 只在run中写, Close在done之后读
+}
+
+// NewAsyncWriter returns an AsyncWriter to out queueing up to size writes,
+// 4096 if size <= 0. Close it to write out the queued data.
+func NewAsyncWriter(out io.Writer, size int) *AsyncWriter {
+	if size <= 0 {
+		size = 4096
+	}
+	w := &AsyncWriter{
+		out:  out,
+		ch:   make(chan []byte, size),
+		done: make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+func (w *AsyncWriter) run() {
+	defer close(w.done)
+	bw := bufio.NewWriterSize(w.out, 64<<10)
+	for p := range w.ch {
+		if _, err := bw.Write(p); err != nil {
+			w.err = err
+			bw.Reset(w.out)
+		}
+		// 队列空了再flush, 负载高时多行合并成一次写
+		if len(w.ch) == 0 {
+			if err := bw.Flush(); err != nil {
+				w.err = err
+				bw.Reset(w.out)
+			}
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		w.err = err
+	}
+}
+
+// Write queues a copy of p. It returns len(p) even if p is dropped because
+// the queue is full, see Dropped.
+func (w *AsyncWriter) Write(p []byte) (int, error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.closed {
+		return 0, ErrAsyncWriterClosed
+	}
+	b := make([]byte, len(p))
+	copy(b, p)
+	select {
+	case w.ch <- b:
+	default:
+		atomic.AddUint64(&w.dropped, 1)
+	}
+	return len(p), nil
+}
+
+// Dropped returns the number of writes dropped because the queue was full.
+func (w *AsyncWriter) Dropped() uint64 {
+	return atomic.LoadUint64(&w.dropped)
+}
+
+// Close writes out the queued data and stops the writer goroutine. It
+// returns the last error writing to out, if any.
+func (w *AsyncWriter) Close() error {
+	w.mu.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.ch)
+	}
+	w.mu.Unlock()
+	<-w.done
+	return w.err
+}
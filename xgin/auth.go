@@ -0,0 +1,133 @@
+package xgin
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// AuthUserKey is the cookie name for user credential in basic auth.
+const AuthUserKey = "user"
+
+// Accounts defines a key/value for user/pass list of authorized logins.
+type Accounts map[string]string
+
+type authPair struct {
+	value string
+	user  string
+}
+
+type authPairs []authPair
+
+// searchCredential 逐个做常量时间比较，不在第一个匹配处提前返回，避免时序攻击
+func (a authPairs) searchCredential(authValue string) (string, bool) {
+	if authValue == "" {
+		return "", false
+	}
+	user, found := "", false
+	for _, pair := range a {
+		if subtle.ConstantTimeCompare([]byte(pair.value), []byte(authValue)) == 1 && !found {
+			user, found = pair.user, true
+		}
+	}
+	return user, found
+}
+
+// BasicAuthForRealm returns a Basic HTTP Authorization middleware. It takes as arguments a map[string]string where
+// the key is the user name and the value is the password, as well as the name of the Realm.
+// If the realm is empty, "Authorization Required" will be used by default.
+// (see http://tools.ietf.org/html/rfc2617#section-1.2)
+func BasicAuthForRealm(accounts Accounts, realm string) HandlerFunc {
+	if realm == "" {
+		realm = "Authorization Required"
+	}
+	realm = "Basic realm=" + strconv.Quote(realm)
+	pairs := processAccounts(accounts)
+	return func(c *Context) {
+		// Search user in the slice of allowed credentials
+		user, found := pairs.searchCredential(c.requestHeader("Authorization"))
+		if !found {
+			// Credentials doesn't match, we return 401 and abort handlers chain.
+			c.Header("WWW-Authenticate", realm)
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+
+		// The user credentials was found, set user's id to key AuthUserKey in this context, the user's id can be read later using
+		// c.MustGet(xgin.AuthUserKey).
+		c.Set(AuthUserKey, user)
+	}
+}
+
+// BasicAuth returns a Basic HTTP Authorization middleware. It takes as argument a map[string]string where
+// the key is the user name and the value is the password.
+func BasicAuth(accounts Accounts) HandlerFunc {
+	return BasicAuthForRealm(accounts, "")
+}
+
+func processAccounts(accounts Accounts) authPairs {
+	if len(accounts) == 0 {
+		panic("Empty list of authorized credentials")
+	}
+	pairs := make(authPairs, 0, len(accounts))
+	for user, password := range accounts {
+		if user == "" {
+			panic("User can not be empty")
+		}
+		value := authorizationHeader(user, password)
+		pairs = append(pairs, authPair{
+			value: value,
+			user:  user,
+		})
+	}
+	return pairs
+}
+
+func authorizationHeader(user, password string) string {
+	base := user + ":" + password
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(base))
+}
+
+// TokenValidator checks a bearer token. It returns the user the token
+// belongs to and whether the token is valid.
+type TokenValidator func(token string) (user interface{}, ok bool)
+
+// BearerAuth returns a middleware that authenticates requests by the token
+// in the "Authorization: Bearer <token>" header (RFC 6750). A valid token's
+// user is stored under AuthUserKey, otherwise the request is aborted with 401.
+func BearerAuth(realm string, validate TokenValidator) HandlerFunc {
+	if validate == nil {
+		panic("TokenValidator can not be nil")
+	}
+	if realm == "" {
+		realm = "Authorization Required"
+	}
+	challenge := "Bearer realm=" + strconv.Quote(realm)
+	return func(c *Context) {
+		token, ok := bearerToken(c.requestHeader("Authorization"))
+		if !ok {
+			c.Header("WWW-Authenticate", challenge)
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		user, ok := validate(token)
+		if !ok {
+			c.Header("WWW-Authenticate", challenge+`, error="invalid_token"`)
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		c.Set(AuthUserKey, user)
+	}
+}
+
+// bearerToken 取出Authorization头中的token, scheme大小写不敏感
+func bearerToken(authValue string) (string, bool) {
+	const prefix = "Bearer "
+	if len(authValue) <= len(prefix) || !strings.EqualFold(authValue[:len(prefix)], prefix) {
+		return "", false
+	}
+	token := strings.TrimSpace(authValue[len(prefix):])
+	return token, token != ""
+}
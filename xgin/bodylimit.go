@@ -0,0 +1,102 @@
+package xgin
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// ErrBodyTooLarge is returned when reading a request body over its limit, see
+// Engine.MaxBodySize and BodyLimit.
+var ErrBodyTooLarge = errors.New("xgin: request body too large")
+
+// limitedBody is a Request.Body that fails with ErrBodyTooLarge past limit.
+// Unlike http.MaxBytesReader its limit can be changed by a route after the
+// engine wrapped the body.
+type limitedBody struct {
+	rc       io.ReadCloser
+	w        http.ResponseWriter
+	limit    int64
+	read     int64
+	exceeded bool
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.exceeded {
+		return 0, ErrBodyTooLarge
+	}
+	// limit可能被BodyLimit调低到已读的长度以下
+	if b.read > b.limit {
+		b.exceed()
+		return 0, ErrBodyTooLarge
+	}
+	// 多读一个字节, 判断是否刚好读到limit
+	if remaining := b.limit - b.read + 1; int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+	n, err := b.rc.Read(p)
+	b.read += int64(n)
+	if b.read > b.limit {
+		n -= int(b.read - b.limit)
+		b.read = b.limit
+		b.exceed()
+		return n, ErrBodyTooLarge
+	}
+	return n, err
+}
+
+func (b *limitedBody) exceed() {
+	b.exceeded = true
+	// 剩余的body不再读取, 连接不能复用
+	b.w.Header().Set("Connection", "close")
+}
+
+func (b *limitedBody) Close() error {
+	return b.rc.Close()
+}
+
+// limitBody sets the body limit of the request, replacing the previous one.
+func (c *Context) limitBody(limit int64) {
+	body := c.Request.Body
+	if body == nil || body == http.NoBody {
+		return
+	}
+	if lb, ok := body.(*limitedBody); ok {
+		lb.limit = limit
+		return
+	}
+	c.Request.Body = &limitedBody{rc: body, w: c.Writer, limit: limit}
+}
+
+// isBodyTooLarge reports whether err comes from reading past the body limit,
+// some decoders don't wrap the read errors.
+func (c *Context) isBodyTooLarge(err error) bool {
+	if errors.Is(err, ErrBodyTooLarge) {
+		return true
+	}
+	lb, ok := c.Request.Body.(*limitedBody)
+	return ok && lb.exceeded
+}
+
+// BodyLimit returns a middleware limiting the request body to limit bytes,
+// it overrides Engine.MaxBodySize for the routes it is used on. Requests whose
+// Content-Length is over limit are rejected with 413 at once, otherwise
+// reading past limit fails with ErrBodyTooLarge.
+//
+//	r.POST("/upload", xgin.BodyLimit(64<<20), upload)
+func BodyLimit(limit int64) HandlerFunc {
+	if limit <= 0 {
+		panic("body limit must be positive")
+	}
+	return func(c *Context) {
+		if c.Request.ContentLength > limit {
+			c.Header("Connection", "close")
+			c.Error(ErrBodyTooLarge).SetMeta(H{"limit": strconv.FormatInt(limit, 10)})
+			c.AbortWithStatus(http.StatusRequestEntityTooLarge)
+			return
+		}
+		c.limitBody(limit)
+		c.Next()
+	}
+}
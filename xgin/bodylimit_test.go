@@ -0,0 +1,114 @@
+package xgin
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLimitedBody(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		limit    int64
+		want     string
+		exceeded bool
+	}{
+		{"under limit", "abc", 5, "abc", false},
+		{"exactly limit", "abcde", 5, "abcde", false},
+		{"limit plus one", "abcdef", 5, "abcde", true},
+		{"far over limit", strings.Repeat("x", 100), 5, "xxxxx", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			b := &limitedBody{rc: io.NopCloser(strings.NewReader(tt.body)), w: w, limit: tt.limit}
+			got, err := io.ReadAll(b)
+			if string(got) != tt.want {
+				t.Errorf("read %q, want %q", got, tt.want)
+			}
+			if tt.exceeded != (err == ErrBodyTooLarge) {
+				t.Errorf("ReadAll error = %v, exceeded %v", err, tt.exceeded)
+			}
+			if close := w.Header().Get("Connection") == "close"; close != tt.exceeded {
+				t.Errorf("Connection: close set = %v, want %v", close, tt.exceeded)
+			}
+			// 超出后再读还是同样的错误
+			if tt.exceeded {
+				if _, err := b.Read(make([]byte, 1)); err != ErrBodyTooLarge {
+					t.Errorf("Read after exceeded error = %v, want ErrBodyTooLarge", err)
+				}
+			}
+		})
+	}
+}
+
+func TestLimitedBodyLowered(t *testing.T) {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("0123456789"))
+	c := &Context{Request: req, Writer: &responseWriter{ResponseWriter: w}}
+	c.limitBody(10)
+
+	p := make([]byte, 5)
+	if n, err := io.ReadFull(c.Request.Body, p); n != 5 || err != nil {
+		t.Fatalf("ReadFull = %d, %v", n, err)
+	}
+	// 路由把limit调低到已读的长度以下
+	c.limitBody(3)
+	if _, ok := c.Request.Body.(*limitedBody); !ok {
+		t.Fatalf("body rewrapped as %T", c.Request.Body)
+	}
+	if n, err := c.Request.Body.Read(p); n != 0 || err != ErrBodyTooLarge {
+		t.Errorf("Read after lowering = %d, %v, want 0, ErrBodyTooLarge", n, err)
+	}
+	if w.Header().Get("Connection") != "close" {
+		t.Errorf("Connection: close not set")
+	}
+	if !c.isBodyTooLarge(nil) {
+		t.Errorf("isBodyTooLarge = false after the limit was exceeded")
+	}
+}
+
+func TestBindJSONBodyTooLarge(t *testing.T) {
+	r := New()
+	r.MaxBodySize = 16
+	var bindErr error
+	r.POST("/json", func(c *Context) {
+		var v map[string]interface{}
+		bindErr = c.BindJSON(&v)
+	})
+	r.POST("/small", BodyLimit(4), func(c *Context) {})
+
+	tests := []struct {
+		name          string
+		path          string
+		body          string
+		contentLength bool
+		code          int
+	}{
+		{"under engine limit", "/json", `{"a":1}`, false, http.StatusOK},
+		{"streamed over engine limit", "/json", `{"a":"0123456789abcdef"}`, false, http.StatusRequestEntityTooLarge},
+		{"content length over BodyLimit", "/small", "hello", true, http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		bindErr = nil
+		req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+		if !tt.contentLength {
+			// 不带Content-Length, 只能在读的时候发现超出
+			req.ContentLength = -1
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tt.code {
+			t.Errorf("%s: POST %s = %d, want %d", tt.name, tt.path, w.Code, tt.code)
+		}
+		if tt.code == http.StatusRequestEntityTooLarge && w.Header().Get("Connection") != "close" {
+			t.Errorf("%s: Connection: close not set", tt.name)
+		}
+		if tt.path == "/json" && (bindErr == ErrBodyTooLarge) != (tt.code == http.StatusRequestEntityTooLarge) {
+			t.Errorf("%s: BindJSON error = %v", tt.name, bindErr)
+		}
+	}
+}
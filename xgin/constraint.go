@@ -0,0 +1,85 @@
+package xgin
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// paramConstraint reports whether a param value is acceptable for a route,
+// ie. the "int" of /users/:id<int>.
+type paramConstraint func(value string) bool
+
+// paramConstraints are the built-in constraint types, "re:" is handled by parseConstraint.
+var paramConstraints = map[string]paramConstraint{
+	"int": func(v string) bool {
+		_, err := strconv.ParseInt(v, 10, 64)
+		return err == nil
+	},
+	"uint": func(v string) bool {
+		_, err := strconv.ParseUint(v, 10, 64)
+		return err == nil
+	},
+	"alpha": func(v string) bool {
+		if v == "" {
+			return false
+		}
+		for i := 0; i < len(v); i++ {
+			if ch := v[i] | 0x20; ch < 'a' || ch > 'z' {
+				return false
+			}
+		}
+		return true
+	},
+	"uuid": isUUID,
+}
+
+// splitWildcard splits ":id<int>" into ":id" and "int".
+// A wildcard without constraint is returned as is.
+func splitWildcard(wildcard, fullPath string) (name, constraint string) {
+	i := strings.IndexByte(wildcard, '<')
+	if i < 0 {
+		return wildcard, ""
+	}
+	if wildcard[len(wildcard)-1] != '>' {
+		panic("unterminated constraint in wildcard '" + wildcard + "' in path '" + fullPath + "'")
+	}
+	return wildcard[:i], wildcard[i+1 : len(wildcard)-1]
+}
+
+// parseConstraint returns the constraint named by spec, "re:<expr>" matches
+// the whole value against the regular expression.
+func parseConstraint(spec, fullPath string) paramConstraint {
+	if strings.HasPrefix(spec, "re:") {
+		re, err := regexp.Compile("^(?:" + spec[len("re:"):] + ")$")
+		if err != nil {
+			panic("invalid constraint '" + spec + "' in path '" + fullPath + "': " + err.Error())
+		}
+		return re.MatchString
+	}
+	if f, ok := paramConstraints[spec]; ok {
+		return f
+	}
+	panic("unknown constraint '" + spec + "' in path '" + fullPath + "'")
+}
+
+// isUUID checks the 8-4-4-4-12 hex form, ie. 123e4567-e89b-12d3-a456-426614174000
+func isUUID(v string) bool {
+	if len(v) != 36 {
+		return false
+	}
+	for i := 0; i < len(v); i++ {
+		ch := v[i]
+		switch i {
+		case 8, 13, 18, 23:
+			if ch != '-' {
+				return false
+			}
+		default:
+			if !('0' <= ch && ch <= '9' || 'a' <= ch && ch <= 'f' || 'A' <= ch && ch <= 'F') {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -1,11 +1,21 @@
 package xgin
 
 import (
+	"encoding/json"
+	"errors"
+	"math"
+	"mime/multipart"
+	"net"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
 	"sync"
 )
 
+// abortIndex represents a typical value used in abort functions.
+const abortIndex int8 = math.MaxInt8 >> 1
+
 // Context is the most important part of gin. It allows us to pass variables between middleware,
 // manage the flow, validate the JSON of a request and render a JSON response for example.
 type Context struct {
@@ -21,12 +31,18 @@ type Context struct {
 	engine *Engine
 	params *Params
 
+	// dispatches counts the Engine.HandleContext calls of the current request.
+	dispatches uint8
+
 	// This mutex protect Keys map
 	mu sync.RWMutex
 
 	// Keys is a key/value pair exclusively for the context of each request.
 	Keys map[string]interface{}
 
+	// Errors is a list of errors attached to all the handlers/middlewares who used this context.
+	Errors errorMsgs
+
 	// Accepted defines a list of manually accepted formats for content negotiation.
 	Accepted []string
 
@@ -50,9 +66,11 @@ func (c *Context) reset() {
 
 	c.fullPath = ""
 	c.Keys = nil
+	c.Errors = c.Errors[:0]
 	c.Accepted = nil
 	c.queryCache = nil
 	c.formCache = nil
+	c.sameSite = 0
 	*c.params = (*c.params)[:0]
 }
 
@@ -64,6 +82,276 @@ func (c *Context) Next() {
 	}
 }
 
+// Abort prevents pending handlers from being called. Note that this will not stop the current handler.
+// Let's say you have an authorization middleware that validates that the current request is authorized.
+// If the authorization fails (ex: the password does not match), call Abort to ensure the remaining handlers
+// for this request are not called.
+func (c *Context) Abort() {
+	c.index = abortIndex
+}
+
+// IsAborted returns true if the current context was aborted.
+func (c *Context) IsAborted() bool {
+	return c.index >= abortIndex
+}
+
+// AbortWithStatus calls `Abort()` and writes the headers with the specified status code.
+// For example, a failed attempt to authenticate a request could use: context.AbortWithStatus(401).
+func (c *Context) AbortWithStatus(code int) {
+	c.Status(code)
+	c.Writer.WriteHeaderNow()
+	c.Abort()
+}
+
+// AbortWithError calls `AbortWithStatus()` and `Error()` internally.
+// This method stops the chain, writes the status code and pushes the specified error to `c.Errors`.
+// See Context.Error() for more details.
+func (c *Context) AbortWithError(code int, err error) *Error {
+	c.AbortWithStatus(code)
+	return c.Error(err)
+}
+
+/************************************/
+/********* ERROR MANAGEMENT *********/
+/************************************/
+
+// Error attaches an error to the current context. The error is pushed to a list of errors.
+// It's a good idea to call Error for each error that occurred during the resolution of a request.
+// A middleware can be used to collect all the errors and push them to a database together,
+// print a log, or append it in the HTTP response.
+// Error will panic if err is nil.
+func (c *Context) Error(err error) *Error {
+	if err == nil {
+		panic("err is nil")
+	}
+
+	parsedError, ok := err.(*Error)
+	if !ok {
+		parsedError = &Error{
+			Err:  err,
+			Type: ErrorTypePrivate,
+		}
+	}
+
+	c.Errors = append(c.Errors, parsedError)
+	return parsedError
+}
+
+// FullPath returns a matched route full path. For not found routes
+// returns an empty string.
+//
+//	router.GET("/user/:id", func(c *gin.Context) {
+//	    c.FullPath() == "/user/:id" // true
+//	})
+func (c *Context) FullPath() string {
+	return c.fullPath
+}
+
+// ClientIP implements a best effort algorithm to return the real client IP.
+// If ForwardedByClientIP is enabled and Request.RemoteAddr is one of the
+// engine.TrustedProxies, the headers listed in engine.RemoteIPHeaders are
+// checked in order, otherwise (or if none of them holds a valid IP) the IP is
+// taken from Request.RemoteAddr.
+func (c *Context) ClientIP() string {
+	remoteIP := c.RemoteIP()
+	if remoteIP == nil {
+		return ""
+	}
+
+	if c.engine.ForwardedByClientIP && c.engine.isTrustedProxy(remoteIP) {
+		for _, headerName := range c.engine.RemoteIPHeaders {
+			if ip, valid := c.engine.validateHeader(c.requestHeader(headerName)); valid {
+				return ip
+			}
+		}
+	}
+	return remoteIP.String()
+}
+
+// RemoteIP parses the IP from Request.RemoteAddr, nil if it is not valid.
+func (c *Context) RemoteIP() net.IP {
+	ip, _, err := net.SplitHostPort(strings.TrimSpace(c.Request.RemoteAddr))
+	if err != nil {
+		return nil
+	}
+	return net.ParseIP(ip)
+}
+
+// Param returns the value of the URL param.
+// It is a shortcut for c.Params.ByName(key)
+//
+//	router.GET("/user/:id", func(c *gin.Context) {
+//	    // a GET request to /user/john
+//	    id := c.Param("id") // id == "john"
+//	})
+func (c *Context) Param(key string) string {
+	return c.Params.ByName(key)
+}
+
+// ParamInt returns the URL param as an int, use it with a :key<int> route.
+func (c *Context) ParamInt(key string) (int, error) {
+	return strconv.Atoi(c.Param(key))
+}
+
+// ParamUint returns the URL param as an uint, use it with a :key<uint> route.
+func (c *Context) ParamUint(key string) (uint, error) {
+	v, err := strconv.ParseUint(c.Param(key), 10, 0)
+	return uint(v), err
+}
+
+// Status sets the HTTP response code.
+func (c *Context) Status(code int) {
+	c.Writer.WriteHeader(code)
+}
+
+// Header is a intelligent shortcut for c.Writer.Header().Set(key, value).
+// It writes a header in the response.
+// If value == "", this method removes the header `c.Writer.Header().Del(key)`
+func (c *Context) Header(key, value string) {
+	if value == "" {
+		c.Writer.Header().Del(key)
+		return
+	}
+	c.Writer.Header().Set(key, value)
+}
+
+// GetHeader returns value from request headers.
+func (c *Context) GetHeader(key string) string {
+	return c.requestHeader(key)
+}
+
+func (c *Context) requestHeader(key string) string {
+	return c.Request.Header.Get(key)
+}
+
+// SetSameSite with cookie
+func (c *Context) SetSameSite(samesite http.SameSite) {
+	c.sameSite = samesite
+}
+
+// SetCookie adds a Set-Cookie header to the ResponseWriter's headers.
+// The provided cookie must have a valid Name. Invalid cookies may be
+// silently dropped.
+func (c *Context) SetCookie(name, value string, maxAge int, path, domain string, secure, httpOnly bool) {
+	if path == "" {
+		path = "/"
+	}
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     name,
+		Value:    url.QueryEscape(value),
+		MaxAge:   maxAge,
+		Path:     path,
+		Domain:   domain,
+		SameSite: c.sameSite,
+		Secure:   secure,
+		HttpOnly: httpOnly,
+	})
+}
+
+// Cookie returns the named cookie provided in the request or
+// ErrNoCookie if not found. And return the named cookie is unescaped.
+// If multiple cookies match the given name, only one cookie will
+// be returned.
+func (c *Context) Cookie(name string) (string, error) {
+	cookie, err := c.Request.Cookie(name)
+	if err != nil {
+		return "", err
+	}
+	val, _ := url.QueryUnescape(cookie.Value)
+	return val, nil
+}
+
+// Push initiates an HTTP/2 server push of target, see http.Pusher. It is a
+// no-op returning nil when the connection doesn't support push, ie. HTTP/1.x
+// or a client that disabled it.
+//
+//	c.Push("/static/app.js", nil)
+func (c *Context) Push(target string, opts *http.PushOptions) error {
+	pusher := c.Writer.Pusher()
+	if pusher == nil {
+		return nil
+	}
+	if err := pusher.Push(target, opts); err != http.ErrNotSupported {
+		return err
+	}
+	return nil
+}
+
+/************************************/
+/************ INPUT DATA ************/
+/************************************/
+
+// ShouldBindJSON decodes the JSON request body into obj. An error reading
+// past the body limit is ErrBodyTooLarge.
+func (c *Context) ShouldBindJSON(obj interface{}) error {
+	if c.Request.Body == nil {
+		return errors.New("invalid request")
+	}
+	if err := json.NewDecoder(c.Request.Body).Decode(obj); err != nil {
+		if c.isBodyTooLarge(err) {
+			return ErrBodyTooLarge
+		}
+		return err
+	}
+	return nil
+}
+
+// BindJSON decodes the JSON request body into obj. On error it aborts with
+// 400, or 413 if the body is over its limit, and adds the error to c.Errors
+// with type ErrorTypeBind.
+func (c *Context) BindJSON(obj interface{}) error {
+	if err := c.ShouldBindJSON(obj); err != nil {
+		code := http.StatusBadRequest
+		if err == ErrBodyTooLarge {
+			code = http.StatusRequestEntityTooLarge
+		}
+		c.AbortWithError(code, err).SetType(ErrorTypeBind)
+		return err
+	}
+	return nil
+}
+
+// MultipartForm is the parsed multipart form, including file uploads.
+// Up to Engine.MaxMultipartMemory bytes of the file parts are kept in memory,
+// the rest is stored on disk. An error reading past the body limit is
+// ErrBodyTooLarge.
+func (c *Context) MultipartForm() (*multipart.Form, error) {
+	if err := c.Request.ParseMultipartForm(c.engine.MaxMultipartMemory); err != nil {
+		if c.isBodyTooLarge(err) {
+			return nil, ErrBodyTooLarge
+		}
+		return nil, err
+	}
+	return c.Request.MultipartForm, nil
+}
+
+// FormFile returns the first file for the provided form key.
+func (c *Context) FormFile(name string) (*multipart.FileHeader, error) {
+	if c.Request.MultipartForm == nil {
+		if _, err := c.MultipartForm(); err != nil {
+			return nil, err
+		}
+	}
+	f, fh, err := c.Request.FormFile(name)
+	if err != nil {
+		return nil, err
+	}
+	f.Close()
+	return fh, err
+}
+
+// JSON serializes the given struct as JSON into the response body.
+// It also sets the Content-Type as "application/json".
+func (c *Context) JSON(code int, obj interface{}) {
+	c.Status(code)
+	c.Header("Content-Type", "application/json; charset=utf-8")
+	jsonBytes, err := json.Marshal(obj)
+	if err != nil {
+		panic(err)
+	}
+	c.Writer.Write(jsonBytes)
+}
+
 func (c *Context) String(s string) {
 	c.Writer.WriteString(s)
 }
@@ -88,3 +376,39 @@ func (c *Context) Get(key string) (value interface{}, exists bool) {
 	c.mu.RUnlock()
 	return
 }
+
+// MustGet returns the value for the given key if it exists, otherwise it panics.
+func (c *Context) MustGet(key string) interface{} {
+	if value, exists := c.Get(key); exists {
+		return value
+	}
+	panic("Key \"" + key + "\" does not exist")
+}
+
+// Copy returns a copy of the current context that can be safely used outside the request's scope.
+// This has to be used when the context has to be passed to a goroutine.
+func (c *Context) Copy() *Context {
+	cp := Context{
+		writermem: c.writermem,
+		Request:   c.Request,
+		Params:    c.Params,
+		engine:    c.engine,
+		fullPath:  c.fullPath,
+
+		dispatches: c.dispatches,
+	}
+	cp.writermem.ResponseWriter = nil
+	cp.Writer = &cp.writermem
+	cp.index = abortIndex
+	cp.handlers = nil
+	cp.Keys = map[string]interface{}{}
+	c.mu.RLock()
+	for k, v := range c.Keys {
+		cp.Keys[k] = v
+	}
+	c.mu.RUnlock()
+	paramCopy := make([]Param, len(cp.Params))
+	copy(paramCopy, cp.Params)
+	cp.Params = paramCopy
+	return &cp
+}
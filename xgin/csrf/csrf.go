@@ -0,0 +1,188 @@
+// Package csrf provides Cross-Site Request Forgery protection for xgin.
+//
+// A random token is issued per session, templates embed it with Token and
+// unsafe requests must send it back in a header or form field:
+//
+//	r.Use(sessions.Sessions(sessionConf), csrf.Middleware(csrf.Config{}))
+//	r.GET("/form", func(c *xgin.Context) {
+//		c.HTML(200, "form.tmpl", xgin.H{"csrf": csrf.Token(c)})
+//	})
+//
+//	<input type="hidden" name="_csrf" value="{{ .csrf }}">
+package csrf
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"net/http"
+
+	"srcrd/xgin"
+	"srcrd/xgin/sessions"
+)
+
+const (
+	tokenKey = "srcrd/xgin/csrf"
+
+	// sessionKey is the session value holding the token.
+	sessionKey = "_csrf_token"
+
+	tokenLength = 32
+)
+
+// ErrInvalidToken is added to the Context errors when a request carries no
+// token or a wrong one.
+var ErrInvalidToken = errors.New("csrf: invalid token")
+
+// Config defines the config for the CSRF middleware.
+type Config struct {
+	// HeaderName and FormField are where the token is read from, default
+	// "X-CSRF-Token" and "_csrf". The header is tried first.
+	HeaderName string
+	FormField  string
+
+	// DoubleSubmit keeps the token in its own cookie instead of the session,
+	// so the sessions middleware isn't needed.
+	DoubleSubmit bool
+
+	// Cookie settings of the double submit mode, CookieName defaults to
+	// "_csrf", CookiePath to "/". The cookie is not HttpOnly, so scripts can
+	// read it and set the header.
+	CookieName     string
+	CookiePath     string
+	CookieDomain   string
+	CookieMaxAge   int
+	CookieSecure   bool
+	CookieSameSite http.SameSite
+
+	// SafeMethods are not checked, default GET, HEAD, OPTIONS and TRACE.
+	SafeMethods []string
+
+	// ErrorHandler is called when the check fails, the default one aborts
+	// with 403 Forbidden.
+	ErrorHandler xgin.HandlerFunc
+}
+
+// Middleware returns a xgin middleware issuing a CSRF token and validating
+// it on unsafe methods. Without DoubleSubmit the sessions middleware must run
+// before it.
+func Middleware(conf Config) xgin.HandlerFunc {
+	if conf.HeaderName == "" {
+		conf.HeaderName = "X-CSRF-Token"
+	}
+	if conf.FormField == "" {
+		conf.FormField = "_csrf"
+	}
+	if conf.CookieName == "" {
+		conf.CookieName = "_csrf"
+	}
+	if conf.CookiePath == "" {
+		conf.CookiePath = "/"
+	}
+	if conf.SafeMethods == nil {
+		conf.SafeMethods = []string{http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace}
+	}
+	if conf.ErrorHandler == nil {
+		conf.ErrorHandler = func(c *xgin.Context) {
+			c.AbortWithStatus(http.StatusForbidden)
+		}
+	}
+	safe := make(map[string]bool, len(conf.SafeMethods))
+	for _, m := range conf.SafeMethods {
+		safe[m] = true
+	}
+
+	return func(c *xgin.Context) {
+		token, err := conf.loadToken(c)
+		if err != nil {
+			c.Error(err)
+			c.AbortWithStatus(http.StatusInternalServerError)
+			return
+		}
+		c.Set(tokenKey, token)
+
+		if !safe[c.Request.Method] && !validToken(token, conf.requestToken(c)) {
+			c.Error(ErrInvalidToken)
+			conf.ErrorHandler(c)
+			return
+		}
+		c.Next()
+	}
+}
+
+// Token returns a token for the request to embed in a form or header. It is
+// masked with a new random pad on each call, so the response doesn't leak the
+// real token to compression attacks like BREACH.
+func Token(c *xgin.Context) string {
+	token := c.MustGet(tokenKey).([]byte)
+	pad := make([]byte, tokenLength)
+	if _, err := rand.Read(pad); err != nil {
+		panic(err)
+	}
+	masked := make([]byte, 2*tokenLength)
+	copy(masked, pad)
+	for i := range token {
+		masked[tokenLength+i] = token[i] ^ pad[i]
+	}
+	return base64.RawURLEncoding.EncodeToString(masked)
+}
+
+// loadToken returns the token of the session or cookie, creating it if needed.
+func (conf *Config) loadToken(c *xgin.Context) ([]byte, error) {
+	if conf.DoubleSubmit {
+		if v, err := c.Cookie(conf.CookieName); err == nil {
+			if token, err := base64.RawURLEncoding.DecodeString(v); err == nil && len(token) == tokenLength {
+				return token, nil
+			}
+		}
+	} else {
+		s := sessions.Default(c)
+		if token, ok := s.Get(sessionKey).([]byte); ok && len(token) == tokenLength {
+			return token, nil
+		}
+	}
+
+	token := make([]byte, tokenLength)
+	if _, err := rand.Read(token); err != nil {
+		return nil, err
+	}
+	if conf.DoubleSubmit {
+		c.SetSameSite(conf.CookieSameSite)
+		c.SetCookie(conf.CookieName, base64.RawURLEncoding.EncodeToString(token), conf.CookieMaxAge,
+			conf.CookiePath, conf.CookieDomain, conf.CookieSecure, false)
+		return token, nil
+	}
+	s := sessions.Default(c)
+	s.Set(sessionKey, token)
+	return token, s.Save()
+}
+
+// requestToken returns the unmasked token sent by the client, nil if none.
+// The raw token is accepted too, it is what scripts read from the double
+// submit cookie.
+func (conf *Config) requestToken(c *xgin.Context) []byte {
+	v := c.GetHeader(conf.HeaderName)
+	if v == "" {
+		v = c.Request.PostFormValue(conf.FormField)
+	}
+	masked, err := base64.RawURLEncoding.DecodeString(v)
+	if err != nil {
+		return nil
+	}
+	if len(masked) == tokenLength {
+		return masked
+	}
+	if len(masked) != 2*tokenLength {
+		return nil
+	}
+	token := make([]byte, tokenLength)
+	for i := range token {
+		token[i] = masked[i] ^ masked[tokenLength+i]
+	}
+	return token
+}
+
+func validToken(token, sent []byte) bool {
+	return len(sent) == tokenLength && subtle.ConstantTimeCompare(token, sent) == 1
+}
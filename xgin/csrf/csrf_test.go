@@ -0,0 +1,142 @@
+package csrf
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"srcrd/xgin"
+	"srcrd/xgin/sessions"
+)
+
+// client keeps the cookies between requests like a browser.
+type client struct {
+	r       http.Handler
+	cookies map[string]string
+}
+
+func (cl *client) do(method, path, header, form string) *httptest.ResponseRecorder {
+	var req *http.Request
+	if form != "" {
+		req = httptest.NewRequest(method, path, strings.NewReader(form))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	} else {
+		req = httptest.NewRequest(method, path, nil)
+	}
+	if header != "" {
+		req.Header.Set("X-CSRF-Token", header)
+	}
+	for name, value := range cl.cookies {
+		req.AddCookie(&http.Cookie{Name: name, Value: value})
+	}
+	w := httptest.NewRecorder()
+	cl.r.ServeHTTP(w, req)
+	for _, cookie := range w.Result().Cookies() {
+		cl.cookies[cookie.Name] = cookie.Value
+	}
+	return w
+}
+
+func newClient(doubleSubmit bool) *client {
+	r := xgin.New()
+	if !doubleSubmit {
+		r.Use(sessions.Sessions(sessions.Config{
+			Keys: []sessions.KeyPair{{HashKey: []byte("0123456789abcdef0123456789abcdef")}},
+		}))
+	}
+	r.Use(Middleware(Config{DoubleSubmit: doubleSubmit}))
+	r.GET("/form", func(c *xgin.Context) {
+		c.String(Token(c))
+	})
+	r.POST("/submit", func(c *xgin.Context) {
+		c.String("ok")
+	})
+	return &client{r: r, cookies: make(map[string]string)}
+}
+
+func TestCSRF(t *testing.T) {
+	for _, mode := range []struct {
+		name         string
+		doubleSubmit bool
+	}{
+		{"session", false},
+		{"double submit", true},
+	} {
+		t.Run(mode.name, func(t *testing.T) {
+			cl := newClient(mode.doubleSubmit)
+			w := cl.do(http.MethodGet, "/form", "", "")
+			if w.Code != http.StatusOK {
+				t.Fatalf("GET /form = %d, want 200", w.Code)
+			}
+			masked := w.Body.String()
+			// 每次取到的token都重新掩码
+			if again := cl.do(http.MethodGet, "/form", "", "").Body.String(); again == masked {
+				t.Errorf("Token() returned the same masked token twice")
+			}
+
+			b, err := base64.RawURLEncoding.DecodeString(masked)
+			if err != nil || len(b) != 2*tokenLength {
+				t.Fatalf("masked token %q: %d bytes, %v", masked, len(b), err)
+			}
+			raw := make([]byte, tokenLength)
+			for i := range raw {
+				raw[i] = b[i] ^ b[tokenLength+i]
+			}
+			rawToken := base64.RawURLEncoding.EncodeToString(raw)
+			wrong := base64.RawURLEncoding.EncodeToString(make([]byte, tokenLength))
+
+			tests := []struct {
+				name   string
+				header string
+				form   string
+				code   int
+			}{
+				{"masked header", masked, "", http.StatusOK},
+				{"raw header", rawToken, "", http.StatusOK},
+				{"masked form", "", url.Values{"_csrf": {masked}}.Encode(), http.StatusOK},
+				{"raw form", "", url.Values{"_csrf": {rawToken}}.Encode(), http.StatusOK},
+				{"missing", "", "", http.StatusForbidden},
+				{"wrong", wrong, "", http.StatusForbidden},
+				{"garbage", "not base64!", "", http.StatusForbidden},
+				{"wrong form", "", url.Values{"_csrf": {wrong}}.Encode(), http.StatusForbidden},
+			}
+			for _, tt := range tests {
+				w := cl.do(http.MethodPost, "/submit", tt.header, tt.form)
+				if w.Code != tt.code {
+					t.Errorf("%s: POST /submit = %d, want %d", tt.name, w.Code, tt.code)
+				}
+			}
+
+			// 没有token时安全的方法照常通过, 不安全的方法被拒绝
+			fresh := newClient(mode.doubleSubmit)
+			if w := fresh.do(http.MethodGet, "/form", "", ""); w.Code != http.StatusOK {
+				t.Errorf("GET /form without cookies = %d, want 200", w.Code)
+			}
+			fresh = newClient(mode.doubleSubmit)
+			if w := fresh.do(http.MethodPost, "/submit", masked, ""); w.Code != http.StatusForbidden {
+				t.Errorf("POST /submit with another client's token = %d, want 403", w.Code)
+			}
+		})
+	}
+}
+
+func TestDoubleSubmitCookie(t *testing.T) {
+	cl := newClient(true)
+	cl.do(http.MethodGet, "/form", "", "")
+	cookie := cl.cookies["_csrf"]
+	if cookie == "" {
+		t.Fatal("no _csrf cookie set")
+	}
+	// 脚本从cookie读出原始token放到header里
+	if w := cl.do(http.MethodPost, "/submit", cookie, ""); w.Code != http.StatusOK {
+		t.Errorf("POST /submit with the cookie value = %d, want 200", w.Code)
+	}
+	// cookie换了, 之前的token不再有效
+	cl.cookies["_csrf"] = base64.RawURLEncoding.EncodeToString(make([]byte, tokenLength))
+	if w := cl.do(http.MethodPost, "/submit", cookie, ""); w.Code != http.StatusForbidden {
+		t.Errorf("POST /submit with a stale token = %d, want 403", w.Code)
+	}
+}
@@ -0,0 +1,243 @@
+package xgin
+
+import (
+	"bufio"
+	"bytes"
+	"hash/fnv"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ETagConfig defines the config for the ETag middleware.
+type ETagConfig struct {
+	// Weak generates weak validators, W/"...", for responses that are
+	// equivalent but not byte for byte identical, ie. compressed later.
+	Weak bool
+
+	// MaxSize is the largest body buffered to compute the ETag, default 1MB.
+	// Larger responses are passed through untouched.
+	MaxSize int
+}
+
+// ETag returns a middleware adding a strong ETag to the successful GET and
+// HEAD responses and answering 304 Not Modified to matching conditional
+// requests.
+func ETag() HandlerFunc {
+	return ETagWithConfig(ETagConfig{})
+}
+
+// ETagWithConfig returns an ETag middleware with config.
+//
+// The response is buffered up to MaxSize. A handler that sets the ETag header
+// itself keeps it, otherwise it is computed from the body. If-None-Match is
+// checked first, If-Modified-Since is only checked without it and against the
+// Last-Modified header set by the handler. Responses larger than MaxSize, or
+// flushed by the handler, ie. streamed, are written as they come.
+func ETagWithConfig(conf ETagConfig) HandlerFunc {
+	if conf.MaxSize <= 0 {
+		conf.MaxSize = 1 << 20
+	}
+
+	return func(c *Context) {
+		if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+			c.Next()
+			return
+		}
+
+		w := &etagWriter{
+			ResponseWriter: c.Writer,
+			maxSize:        conf.MaxSize,
+			status:         defaultStatus,
+			size:           noWritten,
+			buffering:      true,
+		}
+		c.Writer = w
+		defer func() {
+			c.Writer = w.ResponseWriter
+		}()
+		c.Next()
+
+		if w.buffering {
+			w.finish(c.Request, conf.Weak)
+		}
+	}
+}
+
+// etagWriter buffers the response until it is finished or grows over maxSize.
+type etagWriter struct {
+	ResponseWriter
+	buf       bytes.Buffer
+	maxSize   int
+	status    int
+	size      int
+	buffering bool
+}
+
+var _ ResponseWriter = &etagWriter{}
+
+func (w *etagWriter) WriteHeader(code int) {
+	if !w.buffering {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if code > 0 && w.size == noWritten {
+		w.status = code
+	}
+}
+
+func (w *etagWriter) WriteHeaderNow() {
+	if !w.buffering {
+		w.ResponseWriter.WriteHeaderNow()
+		return
+	}
+	if w.size == noWritten {
+		w.size = 0
+	}
+}
+
+func (w *etagWriter) Write(data []byte) (int, error) {
+	if w.buffering && w.buf.Len()+len(data) > w.maxSize {
+		w.passThrough()
+	}
+	if !w.buffering {
+		return w.ResponseWriter.Write(data)
+	}
+	if w.size == noWritten {
+		w.size = 0
+	}
+	n, err := w.buf.Write(data)
+	w.size += n
+	return n, err
+}
+
+func (w *etagWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *etagWriter) Status() int {
+	if !w.buffering {
+		return w.ResponseWriter.Status()
+	}
+	return w.status
+}
+
+func (w *etagWriter) Size() int {
+	if !w.buffering {
+		return w.ResponseWriter.Size()
+	}
+	return w.size
+}
+
+func (w *etagWriter) Written() bool {
+	if !w.buffering {
+		return w.ResponseWriter.Written()
+	}
+	return w.size != noWritten
+}
+
+// Flush sends the buffered response, the rest is streamed without ETag.
+func (w *etagWriter) Flush() {
+	w.passThrough()
+	w.ResponseWriter.Flush()
+}
+
+// Hijack implements the http.Hijacker interface.
+func (w *etagWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.buffering = false
+	return w.ResponseWriter.Hijack()
+}
+
+// passThrough writes out what is buffered and stops buffering.
+func (w *etagWriter) passThrough() {
+	if !w.buffering {
+		return
+	}
+	w.buffering = false
+	if w.size == noWritten {
+		return
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	w.ResponseWriter.WriteHeaderNow()
+	w.ResponseWriter.Write(w.buf.Bytes())
+	w.buf = bytes.Buffer{}
+}
+
+// finish sends the buffered response, or 304 if the request is fulfilled.
+func (w *etagWriter) finish(req *http.Request, weak bool) {
+	w.buffering = false
+	if w.size == noWritten {
+		// 没有写过, 只传递状态码, 由engine发送header
+		w.ResponseWriter.WriteHeader(w.status)
+		return
+	}
+
+	header := w.Header()
+	if w.status == http.StatusOK {
+		etag := header.Get("ETag")
+		if etag == "" {
+			etag = computeETag(w.buf.Bytes(), weak)
+			header.Set("ETag", etag)
+		}
+		if notModified(req, header, etag) {
+			// 304不能带body, 也不需要描述body的header
+			for _, k := range []string{"Content-Type", "Content-Length", "Content-Encoding", "Transfer-Encoding"} {
+				header.Del(k)
+			}
+			w.ResponseWriter.WriteHeader(http.StatusNotModified)
+			w.ResponseWriter.WriteHeaderNow()
+			return
+		}
+	}
+
+	w.ResponseWriter.WriteHeader(w.status)
+	w.ResponseWriter.WriteHeaderNow()
+	w.ResponseWriter.Write(w.buf.Bytes())
+}
+
+// computeETag returns "<length>-<fnv64a>" of body in hex.
+func computeETag(body []byte, weak bool) string {
+	h := fnv.New64a()
+	h.Write(body)
+	tag := `"` + strconv.FormatInt(int64(len(body)), 16) + "-" + strconv.FormatUint(h.Sum64(), 16) + `"`
+	if weak {
+		return "W/" + tag
+	}
+	return tag
+}
+
+// notModified evaluates If-None-Match, or If-Modified-Since without it, see RFC 7232 section 6.
+func notModified(req *http.Request, header http.Header, etag string) bool {
+	if inm := req.Header.Get("If-None-Match"); inm != "" {
+		return etagWeakMatch(inm, etag)
+	}
+	ims := req.Header.Get("If-Modified-Since")
+	lm := header.Get("Last-Modified")
+	if ims == "" || lm == "" {
+		return false
+	}
+	t, err := http.ParseTime(ims)
+	if err != nil {
+		return false
+	}
+	modtime, err := http.ParseTime(lm)
+	if err != nil {
+		return false
+	}
+	return !modtime.Truncate(time.Second).After(t)
+}
+
+// etagWeakMatch reports whether the If-None-Match list contains etag, the
+// comparison is weak: W/"x" matches "x".
+func etagWeakMatch(list, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(list, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -1,14 +1,40 @@
 package xgin
 
 import (
+	"html/template"
 	"log"
+	"net"
 	"net/http"
+	"strings"
 	"sync"
+	"sync/atomic"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+
+	"srcrd/xfasthttp"
 )
 
+const defaultMultipartMemory = 32 << 20 // 32 MB
+
 // HandlerFunc defines the handler used by gin middleware as return value.
 type HandlerFunc func(*Context)
 
+// RouteInfo represents a request route's specification which contains method and path and its handler.
+type RouteInfo struct {
+	// Host is the host pattern of the route, empty for the engine routes.
+	Host        string
+	Method      string
+	Path        string
+	Handler     string
+	HandlerFunc HandlerFunc
+	// Doc is set if the route was registered through RouterGroup.Doc.
+	Doc *RouteDoc
+}
+
+// RoutesInfo defines a RouteInfo array.
+type RoutesInfo []RouteInfo
+
 // HandlersChain defines a HandlerFunc array.
 type HandlersChain []HandlerFunc
 
@@ -65,6 +91,10 @@ type Engine struct {
 	// If enabled, the url.RawPath will be used to find parameters.
 	UseRawPath bool
 
+	// UseH2C enable h2c support: Run serves HTTP/2 over cleartext, with
+	// prior knowledge or through the "Upgrade: h2c" header, besides HTTP/1.1.
+	UseH2C bool
+
 	// If true, the path value will be unescaped.
 	// If UseRawPath is false (by default), the UnescapePathValues effectively is true,
 	// as url.Path gonna be used, which is already unescaped.
@@ -83,7 +113,9 @@ type Engine struct {
 	// List of network origins (IPv4 addresses, IPv4 CIDRs, IPv6 addresses or
 	// IPv6 CIDRs) from which to trust request's headers that contain
 	// alternative client IP when `(*gin.Engine).ForwardedByClientIP` is
-	// `true`.
+	// `true`. None is trusted by default.
+	// It is parsed on the first request, use SetTrustedProxies to change it
+	// afterwards.
 	TrustedProxies []string
 
 	// If set to a constant of value gin.Platform*, trusts the headers set by
@@ -94,18 +126,23 @@ type Engine struct {
 	// method call.
 	MaxMultipartMemory int64
 
-	// delims           render.Delims
+	// MaxBodySize limits the request bodies, 0 means no limit. Reading past it
+	// fails with ErrBodyTooLarge. Routes can override it with BodyLimit.
+	MaxBodySize int64
+
+	delims Delims
 	// secureJSONPrefix string
-	// HTMLRender       render.HTMLRender
-	// FuncMap          template.FuncMap
+	HTMLRender HTMLRender
+	FuncMap    template.FuncMap
 	// allNoRoute       HandlersChain
 	// allNoMethod      HandlersChain
 	// noRoute          HandlersChain
 	// noMethod         HandlersChain
-	pool      sync.Pool
-	trees     methodTrees
-	maxParams uint16
-	// trustedCIDRs     []*net.IPNet
+	pool   sync.Pool
+	routes atomic.Value // *routeTable, 见Engine.SwapRoutes
+	swapMu sync.Mutex
+	// trustedCIDRs是TrustedProxies解析后的结果, []*net.IPNet
+	trustedCIDRs atomic.Value
 }
 
 var _ IRoutes = &Engine{}
@@ -117,8 +154,12 @@ func New() *Engine {
 			basePath: "/",
 			root:     true,
 		},
+		RedirectTrailingSlash: true,
+		RemoteIPHeaders:       []string{"X-Forwarded-For", "X-Real-IP"},
+		MaxMultipartMemory:    defaultMultipartMemory,
 	}
 	engine.RouterGroup.engine = engine
+	engine.routes.Store(&routeTable{})
 	engine.pool.New = func() interface{} {
 		// 复用Context对象
 		return engine.allocateContext()
@@ -133,32 +174,146 @@ func Default() *Engine {
 }
 
 func (engine *Engine) allocateContext() *Context {
-	v := make(Params, 0, engine.maxParams) // maxParams值是会变的
+	v := make(Params, 0, engine.routeTable().maxParams) // maxParams值是会变的
 	return &Context{engine: engine, params: &v}
 }
 
-func (engine *Engine) addRoute(method, path string, handlers HandlersChain) {
-	root := engine.trees.get(method)
-	if root == nil {
-		root = new(node)
-		root.fullPath = "/"
-		engine.trees = append(engine.trees, methodTree{method: method, root: root})
+// SetTrustedProxies sets TrustedProxies, see its doc. It returns an error if
+// an entry is neither an IP nor a CIDR, the previous proxies are kept then.
+func (engine *Engine) SetTrustedProxies(trustedProxies []string) error {
+	cidrs, err := prepareTrustedCIDRs(trustedProxies)
+	if err != nil {
+		return err
 	}
-	root.addRoute(path, handlers)
+	engine.TrustedProxies = trustedProxies
+	engine.trustedCIDRs.Store(cidrs)
+	return nil
+}
 
-	// Update maxParams
-	// if paramsCount := countParams(path); paramsCount > engine.maxParams {
-	// 	engine.maxParams = paramsCount
-	// }
+// isTrustedProxy reports whether ip is one of the TrustedProxies.
+func (engine *Engine) isTrustedProxy(ip net.IP) bool {
+	cidrs, ok := engine.trustedCIDRs.Load().([]*net.IPNet)
+	if !ok {
+		// 直接设置的TrustedProxies, 第一次用到时解析, 无效的忽略
+		var err error
+		if cidrs, err = prepareTrustedCIDRs(engine.TrustedProxies); err != nil {
+			log.Printf("[WARNING] %v, TrustedProxies are ignored", err)
+		}
+		engine.trustedCIDRs.Store(cidrs)
+	}
+	for _, cidr := range cidrs {
+		if cidr.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateHeader returns the client IP of a X-Forwarded-For like header, the
+// rightmost IP that is not a trusted proxy. The entries left of it may be
+// forged by the client.
+func (engine *Engine) validateHeader(header string) (clientIP string, valid bool) {
+	if header == "" {
+		return "", false
+	}
+	// X-Forwarded-For: client, proxy1, proxy2 从右往左跳过可信的代理
+	items := strings.Split(header, ",")
+	for i := len(items) - 1; i >= 0; i-- {
+		ip := net.ParseIP(strings.TrimSpace(items[i]))
+		if ip == nil {
+			break
+		}
+		if i == 0 || !engine.isTrustedProxy(ip) {
+			return ip.String(), true
+		}
+	}
+	return "", false
+}
+
+func prepareTrustedCIDRs(trustedProxies []string) ([]*net.IPNet, error) {
+	cidrs := make([]*net.IPNet, 0, len(trustedProxies))
+	for _, trustedProxy := range trustedProxies {
+		if !strings.Contains(trustedProxy, "/") {
+			ip := net.ParseIP(trustedProxy)
+			if ip == nil {
+				return nil, &net.ParseError{Type: "IP address", Text: trustedProxy}
+			}
+			if ip.To4() != nil {
+				trustedProxy += "/32"
+			} else {
+				trustedProxy += "/128"
+			}
+		}
+		_, cidr, err := net.ParseCIDR(trustedProxy)
+		if err != nil {
+			return nil, err
+		}
+		cidrs = append(cidrs, cidr)
+	}
+	return cidrs, nil
+}
+
+// routeTable returns the routes being served.
+func (engine *Engine) routeTable() *routeTable {
+	return engine.routes.Load().(*routeTable)
+}
+
+// Routes returns a slice of registered routes, including some useful information, such as:
+// the http method, path and the handler name.
+func (engine *Engine) Routes() (routes RoutesInfo) {
+	return engine.routeTable().routesInfo()
+}
+
+func iterate(host, method string, routes RoutesInfo, root *node) RoutesInfo {
+	if len(root.handlers) > 0 {
+		handlerFunc := root.handlers.Last()
+		routes = append(routes, RouteInfo{
+			Host:        host,
+			Method:      method,
+			Path:        root.fullPath,
+			Handler:     nameOfFunction(handlerFunc),
+			HandlerFunc: handlerFunc,
+		})
+	}
+	for _, child := range root.children {
+		routes = iterate(host, method, routes, child)
+	}
+	return routes
 }
 
 // Run attaches the router to a http.Server and starts listening and serving HTTP requests.
 // It is a shortcut for http.ListenAndServe(addr, router)
 // Note: this method will block the calling goroutine indefinitely unless an error happens.
 func (engine *Engine) Run(addr ...string) (err error) {
-	address := ":8080"
+	address := resolveAddress(addr)
 	log.Printf("Listening and serving HTTP on %s\n", address)
-	err = http.ListenAndServe(address, engine)
+	err = http.ListenAndServe(address, engine.Handler())
+	return
+}
+
+// Handler returns the engine as http.Handler, wrapped to serve h2c if
+// UseH2C is enabled. Use it to serve the engine by your own http.Server.
+func (engine *Engine) Handler() http.Handler {
+	if !engine.UseH2C {
+		return engine
+	}
+	h2s := &http2.Server{}
+	return h2c.NewHandler(engine, h2s)
+}
+
+// RunFastHTTP serves the engine by a xfasthttp.Server instead of net/http,
+// connections are handled by its worker pool and every parsed request is
+// converted into a pooled http.Request/ResponseWriter pair.
+// The response is buffered until the handlers return, so Flush is a no-op
+// and Hijack is not supported. Only HTTP/1.x is served, UseH2C is ignored.
+// Note: this method will block the calling goroutine indefinitely unless an error happens.
+func (engine *Engine) RunFastHTTP(addr ...string) (err error) {
+	address := resolveAddress(addr)
+	log.Printf("Listening and serving HTTP on %s with xfasthttp\n", address)
+	s := &xfasthttp.Server{
+		Handler: xfasthttp.NewFastHTTPHandler(engine),
+	}
+	err = s.ListenAndServe(address)
 	return
 }
 
@@ -168,25 +323,83 @@ func (engine *Engine) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	c.writermem.reset(w)
 	c.Request = req
 	c.reset()
+	c.dispatches = 0
 
 	engine.handleHTTPRequest(c)
+	c.writermem.WriteHeaderNow()
 
 	engine.pool.Put(c)
 }
 
+// maxDispatches limits how many times one request can be re-dispatched by
+// HandleContext, so two rewrites pointing at each other can't loop forever.
+const maxDispatches = 10
+
+// HandleContext re-enters a context that has been rewritten.
+// This can be done by setting c.Request.URL.Path to your new target.
+// Disclaimer: You can loop yourself to deal with this, use wisely.
+//
+// The Writer, including one set by a middleware like ETag, the Keys and the
+// Errors are kept, the route is looked up again and its handlers chain runs
+// from the start. When it returns, the handlers left in the calling chain are
+// skipped, the request has already been handled.
+func (engine *Engine) HandleContext(c *Context) {
+	oldHandlers := c.handlers
+
+	c.dispatches++
+	if c.dispatches > maxDispatches {
+		log.Printf("[WARNING] HandleContext: %s %s re-dispatched more than %d times", c.Request.Method, c.Request.URL.Path, maxDispatches)
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	// 只重置路由相关的字段, Writer可能被中间件替换过(ETag, Timeout的Copy), 要保留
+	w := c.Writer
+	c.handlers = nil
+	c.index = -1
+	c.fullPath = ""
+	c.Params = c.Params[:0]
+	if c.params != nil {
+		*c.params = (*c.params)[:0]
+	}
+
+	engine.handleHTTPRequest(c)
+
+	c.Writer = w
+	c.handlers = oldHandlers
+	c.index = int8(len(oldHandlers))
+}
+
 func (engine *Engine) handleHTTPRequest(c *Context) {
 	httpMethod := c.Request.Method
 	rPath := c.Request.URL.Path
+	unescape := false
+	if engine.UseRawPath && len(c.Request.URL.RawPath) > 0 {
+		rPath = c.Request.URL.RawPath
+		unescape = engine.UnescapePathValues
+	}
 
-	// Find root of the tree for the given HTTP method
-	t := engine.trees
+	if engine.MaxBodySize > 0 {
+		c.limitBody(engine.MaxBodySize)
+	}
+
+	// 路由表可能被替换过, 替换后参数更多的话, 池中旧Context的params容量不够
+	rt := engine.routeTable()
+	// Copy出来的Context没有params
+	if c.params == nil || cap(*c.params) < int(rt.maxParams) {
+		v := make(Params, 0, rt.maxParams)
+		c.params = &v
+	}
+
+	// Find root of the tree for the given host and HTTP method
+	t := rt.matchHost(c.Request.Host)
 	for i, tl := 0, len(t); i < tl; i++ {
 		if t[i].method != httpMethod {
 			continue
 		}
 		root := t[i].root
 		// Find route in tree
-		value := root.getValue(rPath, c.params)
+		value := root.getValue(rPath, c.params, unescape)
 		if value.params != nil {
 			c.Params = *value.params
 		}
@@ -195,9 +408,67 @@ func (engine *Engine) handleHTTPRequest(c *Context) {
 			c.handlers = value.handlers
 			c.fullPath = value.fullPath
 			c.Next()
-			c.writermem.WriteHeaderNow()
 			return
 		}
+		if httpMethod != http.MethodConnect && rPath != "/" && value.tsr && engine.RedirectTrailingSlash {
+			// 和gin一样, 重定向和404也经过engine的中间件
+			c.handlers = engine.Handlers
+			redirectTrailingSlash(c)
+			return
+		}
+		break
 	}
 
+	c.handlers = engine.Handlers
+	serveError(c, http.StatusNotFound, default404Body)
+}
+
+// redirectTrailingSlash redirects to the request path with the trailing slash
+// added or removed, after the engine middleware.
+func redirectTrailingSlash(c *Context) {
+	req := c.Request
+	code := http.StatusMovedPermanently // Permanent redirect, request with GET method
+	if req.Method != http.MethodGet {
+		code = http.StatusTemporaryRedirect
+	}
+	c.writermem.status = code
+	c.Next()
+	if c.writermem.Written() {
+		return
+	}
+	if c.writermem.Status() != code {
+		// 中间件改了状态码, 不再重定向
+		c.writermem.WriteHeaderNow()
+		return
+	}
+
+	u := *req.URL
+	if length := len(u.Path); length > 1 && u.Path[length-1] == '/' {
+		u.Path = u.Path[:length-1]
+	} else {
+		u.Path += "/"
+	}
+	// 挂载的Engine重定向到完整路径
+	u.Path = mountPrefix(req) + u.Path
+	// 和URL.Path保持一致, 否则String()会用旧的RawPath
+	u.RawPath = ""
+	http.Redirect(&c.writermem, req, u.String(), code)
+}
+
+var default404Body = []byte("404 page not found")
+
+// serveError runs the engine middleware, then writes defaultMessage if none
+// of them wrote a response or changed the status.
+func serveError(c *Context, code int, defaultMessage []byte) {
+	c.writermem.status = code
+	c.Next()
+	if c.writermem.Written() {
+		return
+	}
+	if c.writermem.Status() == code {
+		c.writermem.Header()["Content-Type"] = []string{"text/plain"}
+		c.writermem.Write(defaultMessage)
+		return
+	}
+	c.writermem.WriteHeaderNow()
 }
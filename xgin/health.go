@@ -0,0 +1,301 @@
+package xgin
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Health statuses of the checks and the reports.
+const (
+	HealthOK           = "ok"
+	HealthDegraded     = "degraded" // 只有非关键检查失败
+	HealthFail         = "fail"
+	HealthShuttingDown = "shutting_down"
+)
+
+const (
+	defaultHealthCheckTimeout = 2 * time.Second
+	defaultHealthCacheTTL     = time.Second
+)
+
+// HealthCheck is a named check of a component the service depends on.
+type HealthCheck struct {
+	// Name identifies the check in the reports, it must be unique.
+	Name string
+
+	// Check returns nil if the component is healthy. It should give up when
+	// ctx is done, its result is ignored after Timeout anyway.
+	Check func(ctx context.Context) error
+
+	// Timeout bounds one run of Check, default 2s.
+	Timeout time.Duration
+
+	// Critical checks fail the report when they fail, the others only
+	// degrade it.
+	Critical bool
+
+	// Liveness checks are also run by /healthz. Only use it for failures a
+	// restart fixes, ie. a deadlock, not for the dependencies: the process
+	// is killed when liveness fails.
+	Liveness bool
+}
+
+// HealthConfig defines the config of a Health registry.
+type HealthConfig struct {
+	// CacheTTL is how long a check result is reused, default 1s. Probes
+	// from many load balancers don't hammer the dependencies.
+	CacheTTL time.Duration
+
+	// ShutdownDelay is how long Shutdown keeps serving after readiness
+	// fails, so the load balancers stop sending new requests first.
+	ShutdownDelay time.Duration
+}
+
+// HealthResult is the last result of a check.
+type HealthResult struct {
+	Status     string    `json:"status"`
+	Critical   bool      `json:"critical"`
+	Error      string    `json:"error,omitempty"`
+	DurationMS float64   `json:"duration_ms"`
+	CheckedAt  time.Time `json:"checked_at"`
+}
+
+// HealthReport is the JSON body of /healthz and /readyz.
+type HealthReport struct {
+	Status string                  `json:"status"`
+	Checks map[string]HealthResult `json:"checks,omitempty"`
+}
+
+// Health is a registry of health checks served as the liveness and
+// readiness probes. The checks run concurrently and their results are
+// cached for CacheTTL, concurrent probes share one run of a check.
+//
+//	health := xgin.NewHealth(xgin.HealthConfig{ShutdownDelay: 5 * time.Second})
+//	health.AddCheck(xgin.HealthCheck{Name: "db", Check: db.PingContext, Critical: true})
+//	health.Register(r)
+//
+//	srv := &http.Server{Addr: ":8080", Handler: r.Handler()}
+//	go srv.ListenAndServe()
+//	<-sigterm
+//	health.Shutdown(ctx, srv)
+//
+// Shut the server down through Health.Shutdown: srv.Shutdown closes the
+// listeners before anything else, so the readiness probe could never report
+// the shutdown if it were failed from there.
+type Health struct {
+	shuttingDown int32
+	conf         HealthConfig
+	mu           sync.RWMutex
+	checks       []*healthEntry
+}
+
+// healthEntry caches the result of a check.
+type healthEntry struct {
+	HealthCheck
+	mu      sync.Mutex
+	result  HealthResult
+	running chan struct{} // 检查进行中时非nil, 结束时关闭
+}
+
+// NewHealth returns an empty Health registry with config.
+func NewHealth(conf HealthConfig) *Health {
+	if conf.CacheTTL <= 0 {
+		conf.CacheTTL = defaultHealthCacheTTL
+	}
+	return &Health{conf: conf}
+}
+
+// AddCheck registers check. It panics if the name is empty or taken.
+func (h *Health) AddCheck(check HealthCheck) {
+	assert1(check.Name != "", "health check name must not be empty")
+	assert1(check.Check != nil, "health check '"+check.Name+"' has no Check func")
+	if check.Timeout <= 0 {
+		check.Timeout = defaultHealthCheckTimeout
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, e := range h.checks {
+		assert1(e.Name != check.Name, "health check '"+check.Name+"' is already registered")
+	}
+	h.checks = append(h.checks, &healthEntry{HealthCheck: check})
+}
+
+// Register adds the GET and HEAD routes /healthz and /readyz to r.
+func (h *Health) Register(r IRoutes) {
+	r.GET("/healthz", h.LivenessHandler())
+	r.HEAD("/healthz", h.LivenessHandler())
+	r.GET("/readyz", h.ReadinessHandler())
+	r.HEAD("/readyz", h.ReadinessHandler())
+}
+
+// LivenessHandler returns the handler of /healthz, it only runs the
+// Liveness checks and keeps succeeding during the shutdown.
+func (h *Health) LivenessHandler() HandlerFunc {
+	return func(c *Context) {
+		h.writeReport(c, h.Check(true))
+	}
+}
+
+// ReadinessHandler returns the handler of /readyz, it runs all the checks
+// and fails at once when the shutdown began.
+func (h *Health) ReadinessHandler() HandlerFunc {
+	return func(c *Context) {
+		if h.ShuttingDown() {
+			h.writeReport(c, HealthReport{Status: HealthShuttingDown})
+			return
+		}
+		h.writeReport(c, h.Check(false))
+	}
+}
+
+func (h *Health) writeReport(c *Context, report HealthReport) {
+	code := http.StatusOK
+	if report.Status == HealthFail || report.Status == HealthShuttingDown {
+		code = http.StatusServiceUnavailable
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(code, report)
+}
+
+// Check runs the checks, only the Liveness ones if liveness is true, and
+// returns the report. Results younger than CacheTTL are reused.
+func (h *Health) Check(liveness bool) HealthReport {
+	h.mu.RLock()
+	entries := make([]*healthEntry, 0, len(h.checks))
+	for _, e := range h.checks {
+		if !liveness || e.Liveness {
+			entries = append(entries, e)
+		}
+	}
+	h.mu.RUnlock()
+
+	results := make([]HealthResult, len(entries))
+	var wg sync.WaitGroup
+	for i, e := range entries {
+		wg.Add(1)
+		go func(i int, e *healthEntry) {
+			defer wg.Done()
+			results[i] = e.get(h.conf.CacheTTL)
+		}(i, e)
+	}
+	wg.Wait()
+
+	report := HealthReport{Status: HealthOK}
+	if len(entries) > 0 {
+		report.Checks = make(map[string]HealthResult, len(entries))
+	}
+	for i, e := range entries {
+		r := results[i]
+		report.Checks[e.Name] = r
+		if r.Status == HealthOK {
+			continue
+		}
+		if r.Critical {
+			report.Status = HealthFail
+		} else if report.Status == HealthOK {
+			report.Status = HealthDegraded
+		}
+	}
+	return report
+}
+
+// get returns the cached result, or runs the check if it is stale. Callers
+// arriving while the check runs wait for its result.
+func (e *healthEntry) get(ttl time.Duration) HealthResult {
+	e.mu.Lock()
+	if !e.result.CheckedAt.IsZero() && time.Since(e.result.CheckedAt) < ttl {
+		r := e.result
+		e.mu.Unlock()
+		return r
+	}
+	if running := e.running; running != nil {
+		e.mu.Unlock()
+		<-running
+		e.mu.Lock()
+		r := e.result
+		e.mu.Unlock()
+		return r
+	}
+	running := make(chan struct{})
+	e.running = running
+	e.mu.Unlock()
+
+	r := e.run()
+
+	e.mu.Lock()
+	e.result = r
+	e.running = nil
+	e.mu.Unlock()
+	close(running)
+	return r
+}
+
+// run runs the check once. The result is shared by the requests, so it
+// isn't bound to any request context.
+func (e *healthEntry) run() HealthResult {
+	ctx, cancel := context.WithTimeout(context.Background(), e.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	errc := make(chan error, 1)
+	go func() {
+		defer func() {
+			if rec := recover(); rec != nil {
+				errc <- fmt.Errorf("panic: %v", rec)
+			}
+		}()
+		errc <- e.Check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-errc:
+	case <-ctx.Done():
+		// 检查没有响应ctx, 不再等待, 它的goroutine自己结束
+		err = fmt.Errorf("timed out after %v", e.Timeout)
+	}
+
+	r := HealthResult{
+		Status:     HealthOK,
+		Critical:   e.Critical,
+		DurationMS: float64(time.Since(start)) / float64(time.Millisecond),
+		CheckedAt:  time.Now(),
+	}
+	if err != nil {
+		r.Status = HealthFail
+		r.Error = err.Error()
+	}
+	return r
+}
+
+// ShuttingDown reports whether the shutdown began, see SetShuttingDown.
+func (h *Health) ShuttingDown() bool {
+	return atomic.LoadInt32(&h.shuttingDown) == 1
+}
+
+// SetShuttingDown fails the readiness probe from now on.
+func (h *Health) SetShuttingDown() {
+	atomic.StoreInt32(&h.shuttingDown, 1)
+}
+
+// Shutdown gracefully shuts down srv: the readiness probe fails first, srv
+// keeps serving for ShutdownDelay so the load balancers take the instance
+// out, then srv.Shutdown(ctx) drains the connections. The delay is cut
+// short when ctx is done.
+func (h *Health) Shutdown(ctx context.Context, srv *http.Server) error {
+	h.SetShuttingDown()
+	if h.conf.ShutdownDelay > 0 {
+		t := time.NewTimer(h.conf.ShutdownDelay)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+		}
+	}
+	return srv.Shutdown(ctx)
+}
@@ -0,0 +1,109 @@
+package xgin
+
+import (
+	"net"
+	"sort"
+	"strings"
+)
+
+// hostTree holds the routes registered for one host pattern.
+type hostTree struct {
+	pattern string // www.example.com 或 *.example.com
+	trees   methodTrees
+}
+
+// Host returns a router group whose routes only match requests for host
+// pattern. pattern is either an exact host name, ie. "api.example.com", or a
+// wildcard "*.example.com" matching any subdomain of example.com but not
+// example.com itself. The port of the request Host is ignored.
+//
+// Exact patterns take precedence over wildcards, and the longest wildcard
+// wins. Requests whose host matches a pattern are only routed in that host's
+// routes, the routes registered on the engine are the fallback for requests
+// that match no pattern.
+//
+// handlers are the host's middleware, they run after the engine middleware
+// registered so far.
+//
+//	api := r.Host("api.example.com", auth)
+//	api.GET("/users", listUsers)
+//	tenants := r.Host("*.example.com")
+//	tenants.GET("/", tenantHome)
+func (engine *Engine) Host(pattern string, handlers ...HandlerFunc) *RouterGroup {
+	return engine.hostGroup(pattern, handlers)
+}
+
+func (group *RouterGroup) hostGroup(pattern string, handlers HandlersChain) *RouterGroup {
+	pattern = strings.ToLower(pattern)
+	name := strings.TrimPrefix(pattern, "*.")
+	assert1(name != "" && !strings.ContainsAny(name, "*/:"), "invalid host pattern '"+pattern+"'")
+
+	return &RouterGroup{
+		Handlers: group.combineHandlers(handlers),
+		basePath: group.basePath,
+		engine:   group.engine,
+		table:    group.table,
+		host:     pattern,
+	}
+}
+
+// hostTrees returns the routes of host pattern, registering it if needed.
+func (t *routeTable) hostTrees(pattern string) *methodTrees {
+	for _, h := range t.hosts {
+		if h.pattern == pattern {
+			return &h.trees
+		}
+	}
+	h := &hostTree{pattern: pattern}
+	t.hosts = append(t.hosts, h)
+	// 精确匹配在前, 通配按后缀长度从长到短, 查找时取第一个匹配的
+	sort.SliceStable(t.hosts, func(i, j int) bool {
+		wi := strings.HasPrefix(t.hosts[i].pattern, "*.")
+		wj := strings.HasPrefix(t.hosts[j].pattern, "*.")
+		if wi != wj {
+			return !wi
+		}
+		return len(t.hosts[i].pattern) > len(t.hosts[j].pattern)
+	})
+	return &h.trees
+}
+
+// matchHost returns the routes for the request host, the default routes if
+// no host pattern matches.
+func (t *routeTable) matchHost(host string) methodTrees {
+	if h := t.hostOf(host); h != nil {
+		return h.trees
+	}
+	return t.trees
+}
+
+// hostOf returns the host pattern the request host matches, nil if none.
+func (t *routeTable) hostOf(host string) *hostTree {
+	if len(t.hosts) == 0 {
+		return nil
+	}
+	host = strings.ToLower(stripHostPort(host))
+	for _, h := range t.hosts {
+		if h.pattern[0] != '*' {
+			if h.pattern == host {
+				return h
+			}
+			continue
+		}
+		suffix := h.pattern[1:] // .example.com
+		if len(host) > len(suffix) && strings.HasSuffix(host, suffix) {
+			return h
+		}
+	}
+	return nil
+}
+
+// stripHostPort returns h without any trailing ":<port>" or dot.
+func stripHostPort(h string) string {
+	if strings.IndexByte(h, ':') >= 0 {
+		if host, _, err := net.SplitHostPort(h); err == nil {
+			h = host
+		}
+	}
+	return strings.TrimSuffix(h, ".")
+}
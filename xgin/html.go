@@ -0,0 +1,134 @@
+package xgin
+
+import (
+	"html/template"
+	"net/http"
+)
+
+// Delims represents a set of Left and Right delimiters for HTML template rendering.
+type Delims struct {
+	// Left delimiter, defaults to {{.
+	Left string
+	// Right delimiter, defaults to }}.
+	Right string
+}
+
+// HTMLRender executes the named template with data into w.
+type HTMLRender interface {
+	Render(w http.ResponseWriter, name string, data interface{}) error
+}
+
+// HTMLProduction renders a template set parsed once.
+type HTMLProduction struct {
+	Template *template.Template
+}
+
+// HTMLDebug re-parses the files or the glob on every render, so the changes
+// of the templates show up without a restart.
+type HTMLDebug struct {
+	Files   []string
+	Glob    string
+	Delims  Delims
+	FuncMap template.FuncMap
+}
+
+var (
+	_ HTMLRender = HTMLProduction{}
+	_ HTMLRender = HTMLDebug{}
+)
+
+// Render implements the HTMLRender interface.
+func (r HTMLProduction) Render(w http.ResponseWriter, name string, data interface{}) error {
+	return executeTemplate(w, r.Template, name, data)
+}
+
+// Render implements the HTMLRender interface.
+func (r HTMLDebug) Render(w http.ResponseWriter, name string, data interface{}) error {
+	templ, err := r.loadTemplate()
+	if err != nil {
+		return err
+	}
+	return executeTemplate(w, templ, name, data)
+}
+
+func (r HTMLDebug) loadTemplate() (*template.Template, error) {
+	t := template.New("").Delims(r.Delims.Left, r.Delims.Right).Funcs(r.FuncMap)
+	if len(r.Files) > 0 {
+		return t.ParseFiles(r.Files...)
+	}
+	if r.Glob != "" {
+		return t.ParseGlob(r.Glob)
+	}
+	panic("the HTML debug render was created without files or glob pattern")
+}
+
+func executeTemplate(w http.ResponseWriter, templ *template.Template, name string, data interface{}) error {
+	header := w.Header()
+	if val := header["Content-Type"]; len(val) == 0 {
+		header["Content-Type"] = []string{"text/html; charset=utf-8"}
+	}
+	if name == "" {
+		return templ.Execute(w, data)
+	}
+	return templ.ExecuteTemplate(w, name, data)
+}
+
+// Delims sets template left and right delims and returns an Engine instance.
+// It must be called before LoadHTMLGlob or LoadHTMLFiles.
+func (engine *Engine) Delims(left, right string) *Engine {
+	engine.delims = Delims{Left: left, Right: right}
+	return engine
+}
+
+// SetFuncMap sets the FuncMap used for the templates. It must be called
+// before LoadHTMLGlob or LoadHTMLFiles.
+func (engine *Engine) SetFuncMap(funcMap template.FuncMap) {
+	engine.FuncMap = funcMap
+}
+
+// LoadHTMLGlob loads HTML files identified by glob pattern
+// and associates the result with HTML renderer.
+// In debug mode the files are parsed again on every Context.HTML.
+func (engine *Engine) LoadHTMLGlob(pattern string) {
+	left := engine.delims.Left
+	right := engine.delims.Right
+	// 先解析一次, 模板有错误时启动即panic
+	templ := template.Must(template.New("").Delims(left, right).Funcs(engine.FuncMap).ParseGlob(pattern))
+
+	if IsDebugging() {
+		engine.HTMLRender = HTMLDebug{Glob: pattern, FuncMap: engine.FuncMap, Delims: engine.delims}
+		return
+	}
+	engine.SetHTMLTemplate(templ)
+}
+
+// LoadHTMLFiles loads a slice of HTML files
+// and associates the result with HTML renderer.
+// In debug mode the files are parsed again on every Context.HTML.
+func (engine *Engine) LoadHTMLFiles(files ...string) {
+	templ := template.Must(template.New("").Delims(engine.delims.Left, engine.delims.Right).Funcs(engine.FuncMap).ParseFiles(files...))
+
+	if IsDebugging() {
+		engine.HTMLRender = HTMLDebug{Files: files, FuncMap: engine.FuncMap, Delims: engine.delims}
+		return
+	}
+	engine.SetHTMLTemplate(templ)
+}
+
+// SetHTMLTemplate associate a template with HTML renderer.
+func (engine *Engine) SetHTMLTemplate(templ *template.Template) {
+	engine.HTMLRender = HTMLProduction{Template: templ.Funcs(engine.FuncMap)}
+}
+
+// HTML renders the HTTP template specified by its file name.
+// It also updates the HTTP code and sets the Content-Type as "text/html".
+// See http://golang.org/doc/articles/wiki/
+func (c *Context) HTML(code int, name string, obj interface{}) {
+	if c.engine.HTMLRender == nil {
+		panic("xgin: no HTML templates loaded, call LoadHTMLGlob or LoadHTMLFiles first")
+	}
+	c.Status(code)
+	if err := c.engine.HTMLRender.Render(c.Writer, name, obj); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,109 @@
+package xgin
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// DefaultWriter is the default io.Writer used by xgin for debug output and
+// middleware output like Logger().
+var DefaultWriter io.Writer = os.Stdout
+
+// LogFormatterParams is the structure any formatter will be handed when time to log comes
+type LogFormatterParams struct {
+	Request *Context
+
+	// TimeStamp shows the time after the server returns a response.
+	TimeStamp time.Time
+	// StatusCode is HTTP response code.
+	StatusCode int
+	// Latency is how much time the server cost to process a certain request.
+	Latency time.Duration
+	// ClientIP equals Context's ClientIP method.
+	ClientIP string
+	// Method is the HTTP method given to the request.
+	Method string
+	// Path is a path the client requests.
+	Path string
+	// FullPath is the matched route, ie. /user/:id, empty if no route matched.
+	FullPath string
+	// BodySize is the size of the Response Body
+	BodySize int
+	// RequestID is the id set by the RequestID middleware, empty if it is not used.
+	RequestID string
+	// TraceID is the W3C trace id set by the RequestID middleware.
+	TraceID string
+}
+
+// LogFormatter gives the signature of the formatter function passed to LoggerWithFormatter
+type LogFormatter func(params LogFormatterParams) string
+
+// defaultLogFormatter is the default log format function Logger middleware uses.
+var defaultLogFormatter = func(param LogFormatterParams) string {
+	if param.Latency > time.Minute {
+		// Truncate in a golang < 1.8 safe way
+		param.Latency = param.Latency - param.Latency%time.Second
+	}
+	s := fmt.Sprintf("[GIN] %v | %3d | %13v | %15s | %-7s %#v",
+		param.TimeStamp.Format("2006/01/02 - 15:04:05"),
+		param.StatusCode,
+		param.Latency,
+		param.ClientIP,
+		param.Method,
+		param.Path,
+	)
+	if param.RequestID != "" {
+		s += " | " + param.RequestID
+	}
+	if param.TraceID != "" {
+		s += " | trace=" + param.TraceID
+	}
+	return s + "\n"
+}
+
+// Logger instances a Logger middleware that will write the logs to xgin.DefaultWriter.
+// Use it after RequestID() to get the request and trace ids in the output.
+func Logger() HandlerFunc {
+	return LoggerWithFormatter(defaultLogFormatter, DefaultWriter)
+}
+
+// LoggerWithWriter instance a Logger middleware with the specified writer buffer.
+func LoggerWithWriter(out io.Writer) HandlerFunc {
+	return LoggerWithFormatter(defaultLogFormatter, out)
+}
+
+// LoggerWithFormatter instance a Logger middleware with the specified log format function.
+func LoggerWithFormatter(f LogFormatter, out io.Writer) HandlerFunc {
+	return func(c *Context) {
+		// Start timer
+		start := time.Now()
+		path := c.Request.URL.Path
+		raw := c.Request.URL.RawQuery
+
+		// Process request
+		c.Next()
+
+		param := LogFormatterParams{
+			Request:    c,
+			TimeStamp:  time.Now(),
+			ClientIP:   c.ClientIP(),
+			Method:     c.Request.Method,
+			FullPath:   c.FullPath(),
+			StatusCode: c.Writer.Status(),
+			BodySize:   c.Writer.Size(),
+			RequestID:  c.RequestID(),
+		}
+		param.Latency = param.TimeStamp.Sub(start)
+		if tc, ok := c.TraceContext(); ok {
+			param.TraceID = tc.TraceID
+		}
+		if raw != "" {
+			path = path + "?" + raw
+		}
+		param.Path = path
+
+		fmt.Fprint(out, f(param))
+	}
+}
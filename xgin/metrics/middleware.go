@@ -0,0 +1,51 @@
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"srcrd/xgin"
+)
+
+// Middleware returns a xgin middleware that records into reg, per matched
+// route (Context.FullPath):
+//
+//	xgin_requests_total{method,path,status}
+//	xgin_request_duration_seconds{method,path}
+//	xgin_response_size_bytes_total{method,path,status}
+//	xgin_requests_in_flight{method,path}
+//
+// It registers these metrics, so it can be called once per Registry.
+func Middleware(reg *Registry) xgin.HandlerFunc {
+	requests := reg.NewCounterVec("xgin_requests_total", "Total number of HTTP requests.", "method", "path", "status")
+	latency := reg.NewHistogramVec("xgin_request_duration_seconds", "HTTP request latency in seconds.", DefBuckets, "method", "path")
+	size := reg.NewCounterVec("xgin_response_size_bytes_total", "Total bytes written into HTTP response bodies.", "method", "path", "status")
+	inFlight := reg.NewGaugeVec("xgin_requests_in_flight", "Number of HTTP requests being served.", "method", "path")
+
+	return func(c *xgin.Context) {
+		method, path := c.Request.Method, c.FullPath()
+		gauge := inFlight.WithLabelValues(method, path)
+		gauge.Inc()
+		start := time.Now()
+
+		defer func() {
+			gauge.Dec()
+			status := strconv.Itoa(c.Writer.Status())
+			requests.WithLabelValues(method, path, status).Inc()
+			latency.WithLabelValues(method, path).Observe(time.Since(start).Seconds())
+			if n := c.Writer.Size(); n > 0 {
+				size.WithLabelValues(method, path, status).Add(float64(n))
+			}
+		}()
+		c.Next()
+	}
+}
+
+// Handler returns a xgin handler serving reg, ie.
+//
+//	r.GET("/metrics", metrics.Handler(reg))
+func Handler(reg *Registry) xgin.HandlerFunc {
+	return func(c *xgin.Context) {
+		reg.ServeHTTP(c.Writer, c.Request)
+	}
+}
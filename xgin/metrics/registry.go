@@ -0,0 +1,336 @@
+// Package metrics is a small counters/gauges/histograms registry that
+// renders the Prometheus text exposition format, plus xgin middleware
+// recording request metrics.
+// See https://prometheus.io/docs/instrumenting/exposition_formats/
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+const contentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefBuckets are the default latency buckets in seconds.
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Registry holds metric families and renders them in registration order.
+type Registry struct {
+	mu       sync.RWMutex
+	families []*family
+	names    map[string]bool
+}
+
+// NewRegistry returns an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{names: make(map[string]bool)}
+}
+
+// NewCounterVec registers a counter family partitioned by labels.
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	return &CounterVec{r.register(name, help, "counter", labels, nil)}
+}
+
+// NewGaugeVec registers a gauge family partitioned by labels.
+func (r *Registry) NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	return &GaugeVec{r.register(name, help, "gauge", labels, nil)}
+}
+
+// NewHistogramVec registers a histogram family partitioned by labels.
+// buckets are the upper bounds, they are sorted and DefBuckets is used if empty.
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	if len(buckets) == 0 {
+		buckets = DefBuckets
+	}
+	b := append([]float64(nil), buckets...)
+	sort.Float64s(b)
+	return &HistogramVec{r.register(name, help, "histogram", labels, b)}
+}
+
+func (r *Registry) register(name, help, typ string, labels []string, buckets []float64) *family {
+	if !validName(name) {
+		panic("metrics: invalid metric name " + strconv.Quote(name))
+	}
+	for _, l := range labels {
+		if !validName(l) || l == "le" {
+			panic("metrics: invalid label name " + strconv.Quote(l))
+		}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.names[name] {
+		panic("metrics: duplicate metric " + strconv.Quote(name))
+	}
+	r.names[name] = true
+	f := &family{
+		name:    name,
+		help:    help,
+		typ:     typ,
+		labels:  labels,
+		buckets: buckets,
+		series:  make(map[string]*series),
+	}
+	r.families = append(r.families, f)
+	return f
+}
+
+// WriteTo writes all metrics in the Prometheus text format.
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	cw := &countWriter{w: bufio.NewWriter(w)}
+	r.mu.RLock()
+	families := r.families
+	r.mu.RUnlock()
+	for _, f := range families {
+		f.write(cw)
+	}
+	if cw.err == nil {
+		cw.err = cw.w.Flush()
+	}
+	return cw.n, cw.err
+}
+
+// ServeHTTP implements http.Handler, it serves the metrics page.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", contentType)
+	r.WriteTo(w)
+}
+
+// family is all series of one metric name.
+type family struct {
+	name    string
+	help    string
+	typ     string
+	labels  []string
+	buckets []float64
+
+	mu     sync.RWMutex
+	series map[string]*series
+}
+
+type series struct {
+	labelValues []string
+	value       uint64   // float64 bits, counter and gauge
+	counts      []uint64 // per bucket, not cumulative
+	count       uint64
+	sum         uint64 // float64 bits
+}
+
+// with returns the series of the label values, creating it on first use.
+func (f *family) with(values []string) *series {
+	if len(values) != len(f.labels) {
+		panic("metrics: " + f.name + " expects " + strconv.Itoa(len(f.labels)) + " label values")
+	}
+	key := strings.Join(values, "\xff")
+	f.mu.RLock()
+	s := f.series[key]
+	f.mu.RUnlock()
+	if s != nil {
+		return s
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if s = f.series[key]; s == nil {
+		s = &series{labelValues: append([]string(nil), values...)}
+		if f.typ == "histogram" {
+			s.counts = make([]uint64, len(f.buckets))
+		}
+		f.series[key] = s
+	}
+	return s
+}
+
+func (f *family) write(w *countWriter) {
+	f.mu.RLock()
+	keys := make([]string, 0, len(f.series))
+	for k := range f.series {
+		keys = append(keys, k)
+	}
+	f.mu.RUnlock()
+	sort.Strings(keys)
+
+	w.WriteString("# HELP " + f.name + " " + escapeHelp(f.help) + "\n")
+	w.WriteString("# TYPE " + f.name + " " + f.typ + "\n")
+	for _, k := range keys {
+		f.mu.RLock()
+		s := f.series[k]
+		f.mu.RUnlock()
+		if f.typ != "histogram" {
+			w.WriteString(f.name + f.labelString(s.labelValues, "") + " " + formatFloat(loadFloat(&s.value)) + "\n")
+			continue
+		}
+
+		var cumulative uint64
+		for i, upper := range f.buckets {
+			cumulative += atomic.LoadUint64(&s.counts[i])
+			w.WriteString(f.name + "_bucket" + f.labelString(s.labelValues, formatFloat(upper)) + " " + strconv.FormatUint(cumulative, 10) + "\n")
+		}
+		count := atomic.LoadUint64(&s.count)
+		w.WriteString(f.name + "_bucket" + f.labelString(s.labelValues, "+Inf") + " " + strconv.FormatUint(count, 10) + "\n")
+		w.WriteString(f.name + "_sum" + f.labelString(s.labelValues, "") + " " + formatFloat(loadFloat(&s.sum)) + "\n")
+		w.WriteString(f.name + "_count" + f.labelString(s.labelValues, "") + " " + strconv.FormatUint(count, 10) + "\n")
+	}
+}
+
+// labelString renders {a="1",b="2"}, with an additional le label for histogram buckets.
+func (f *family) labelString(values []string, le string) string {
+	if len(values) == 0 && le == "" {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, name := range f.labels {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(name + `="` + escapeLabel(values[i]) + `"`)
+	}
+	if le != "" {
+		if len(values) > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(`le="` + le + `"`)
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// CounterVec is a counter partitioned by label values.
+type CounterVec struct{ f *family }
+
+// WithLabelValues returns the counter of the label values.
+func (v *CounterVec) WithLabelValues(values ...string) Counter {
+	return Counter{v.f.with(values)}
+}
+
+// Counter is a monotonically increasing value.
+type Counter struct{ s *series }
+
+// Inc increments the counter by 1.
+func (c Counter) Inc() { c.Add(1) }
+
+// Add adds v to the counter, v must not be negative.
+func (c Counter) Add(v float64) {
+	if v < 0 {
+		panic("metrics: counter cannot decrease")
+	}
+	addFloat(&c.s.value, v)
+}
+
+// GaugeVec is a gauge partitioned by label values.
+type GaugeVec struct{ f *family }
+
+// WithLabelValues returns the gauge of the label values.
+func (v *GaugeVec) WithLabelValues(values ...string) Gauge {
+	return Gauge{v.f.with(values)}
+}
+
+// Gauge is a value that can go up and down.
+type Gauge struct{ s *series }
+
+// Set sets the gauge to v.
+func (g Gauge) Set(v float64) { atomic.StoreUint64(&g.s.value, math.Float64bits(v)) }
+
+// Add adds v to the gauge.
+func (g Gauge) Add(v float64) { addFloat(&g.s.value, v) }
+
+// Inc increments the gauge by 1.
+func (g Gauge) Inc() { g.Add(1) }
+
+// Dec decrements the gauge by 1.
+func (g Gauge) Dec() { g.Add(-1) }
+
+// HistogramVec is a histogram partitioned by label values.
+type HistogramVec struct{ f *family }
+
+// WithLabelValues returns the histogram of the label values.
+func (v *HistogramVec) WithLabelValues(values ...string) Histogram {
+	return Histogram{v.f.with(values), v.f.buckets}
+}
+
+// Histogram counts observations in buckets.
+type Histogram struct {
+	s       *series
+	buckets []float64
+}
+
+// Observe adds one observation.
+func (h Histogram) Observe(v float64) {
+	// 第一个 >= v 的bucket, 超出所有bucket的只计入+Inf(即count)
+	if i := sort.SearchFloat64s(h.buckets, v); i < len(h.buckets) {
+		atomic.AddUint64(&h.s.counts[i], 1)
+	}
+	addFloat(&h.s.sum, v)
+	atomic.AddUint64(&h.s.count, 1)
+}
+
+func addFloat(addr *uint64, v float64) {
+	for {
+		old := atomic.LoadUint64(addr)
+		n := math.Float64bits(math.Float64frombits(old) + v)
+		if atomic.CompareAndSwapUint64(addr, old, n) {
+			return
+		}
+	}
+}
+
+func loadFloat(addr *uint64) float64 {
+	return math.Float64frombits(atomic.LoadUint64(addr))
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string  { return helpEscaper.Replace(s) }
+func escapeLabel(s string) string { return labelEscaper.Replace(s) }
+
+// validName checks [a-zA-Z_:][a-zA-Z0-9_:]*
+func validName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		ch := name[i]
+		if ch == '_' || ch == ':' || 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || i > 0 && '0' <= ch && ch <= '9' {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+type countWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (cw *countWriter) WriteString(s string) {
+	if cw.err != nil {
+		return
+	}
+	n, err := cw.w.WriteString(s)
+	cw.n += int64(n)
+	cw.err = err
+}
@@ -0,0 +1,48 @@
+package xgin
+
+import (
+	"os"
+	"sync/atomic"
+)
+
+// EnvXginMode indicates environment name for xgin mode.
+const EnvXginMode = "XGIN_MODE"
+
+const (
+	// DebugMode indicates xgin mode is debug.
+	DebugMode = "debug"
+	// ReleaseMode indicates xgin mode is release.
+	ReleaseMode = "release"
+	// TestMode indicates xgin mode is test.
+	TestMode = "test"
+)
+
+var xginMode atomic.Value
+
+func init() {
+	SetMode(os.Getenv(EnvXginMode))
+}
+
+// SetMode sets xgin mode according to input string, an empty value is DebugMode.
+func SetMode(value string) {
+	if value == "" {
+		value = DebugMode
+	}
+	switch value {
+	case DebugMode, ReleaseMode, TestMode:
+	default:
+		panic("xgin mode unknown: " + value + " (available mode: debug release test)")
+	}
+	xginMode.Store(value)
+}
+
+// Mode returns current xgin mode.
+func Mode() string {
+	return xginMode.Load().(string)
+}
+
+// IsDebugging returns true if the framework is running in debug mode.
+// Use SetMode(xgin.ReleaseMode) to disable debug mode.
+func IsDebugging() bool {
+	return Mode() == DebugMode
+}
@@ -0,0 +1,418 @@
+package xgin
+
+import (
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RouteDoc is the optional OpenAPI metadata of a route, see RouterGroup.Doc.
+type RouteDoc struct {
+	Summary     string
+	Description string
+	Tags        []string
+	OperationID string
+	Deprecated  bool
+
+	// Request is a value of the type the handler binds. For GET, HEAD and
+	// DELETE routes its fields are query parameters named by the `form` tag,
+	// otherwise it is the JSON request body.
+	Request interface{}
+
+	// Response is a value of the type the handler renders as JSON with
+	// ResponseStatus, which defaults to 200.
+	Response       interface{}
+	ResponseStatus int
+}
+
+// OpenAPIInfo is the info object of the generated document.
+type OpenAPIInfo struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// OpenAPIDocument is an OpenAPI 3 document, limited to what can be derived
+// from the registered routes.
+type OpenAPIDocument struct {
+	OpenAPI    string                                  `json:"openapi"`
+	Info       OpenAPIInfo                             `json:"info"`
+	Paths      map[string]map[string]*openAPIOperation `json:"paths"`
+	Components *openAPIComponents                      `json:"components,omitempty"`
+}
+
+type openAPIOperation struct {
+	OperationID string                      `json:"operationId,omitempty"`
+	Summary     string                      `json:"summary,omitempty"`
+	Description string                      `json:"description,omitempty"`
+	Tags        []string                    `json:"tags,omitempty"`
+	Deprecated  bool                        `json:"deprecated,omitempty"`
+	Parameters  []*openAPIParameter         `json:"parameters,omitempty"`
+	RequestBody *openAPIRequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*openAPIResponse `json:"responses"`
+}
+
+type openAPIParameter struct {
+	Name     string         `json:"name"`
+	In       string         `json:"in"`
+	Required bool           `json:"required,omitempty"`
+	Schema   *openAPISchema `json:"schema"`
+}
+
+type openAPIRequestBody struct {
+	Required bool                         `json:"required"`
+	Content  map[string]*openAPIMediaType `json:"content"`
+}
+
+type openAPIResponse struct {
+	Description string                       `json:"description"`
+	Content     map[string]*openAPIMediaType `json:"content,omitempty"`
+}
+
+type openAPIMediaType struct {
+	Schema *openAPISchema `json:"schema"`
+}
+
+type openAPIComponents struct {
+	Schemas map[string]*openAPISchema `json:"schemas,omitempty"`
+}
+
+type openAPISchema struct {
+	Ref                  string                    `json:"$ref,omitempty"`
+	Type                 string                    `json:"type,omitempty"`
+	Format               string                    `json:"format,omitempty"`
+	Pattern              string                    `json:"pattern,omitempty"`
+	Minimum              *float64                  `json:"minimum,omitempty"`
+	Items                *openAPISchema            `json:"items,omitempty"`
+	Properties           map[string]*openAPISchema `json:"properties,omitempty"`
+	AdditionalProperties *openAPISchema            `json:"additionalProperties,omitempty"`
+	Required             []string                  `json:"required,omitempty"`
+}
+
+// OpenAPI generates an OpenAPI 3 document from the routes registered for the
+// host pattern host, see Engine.Host, or from the engine routes if host is
+// empty. Each host gets its own document since the same path can be
+// registered on several hosts.
+// Path parameters are inferred from the route syntax, :id<int> becomes
+// an integer parameter {id}. Routes registered with RouterGroup.Doc get their
+// summary, tags and request/response schemas, the schemas are built by
+// reflection following the `json` tags. CONNECT routes, ie. those of Any
+// and Mount, are skipped since OpenAPI can't describe them.
+func (engine *Engine) OpenAPI(host string, info OpenAPIInfo) *OpenAPIDocument {
+	doc := &OpenAPIDocument{
+		OpenAPI: "3.0.3",
+		Info:    info,
+		Paths:   make(map[string]map[string]*openAPIOperation),
+	}
+	g := &schemaGenerator{
+		schemas: make(map[string]*openAPISchema),
+		names:   make(map[reflect.Type]string),
+	}
+
+	host = strings.ToLower(host)
+	for _, route := range engine.Routes() {
+		// Path Item Object没有CONNECT, Any和Mount注册的CONNECT路由不输出
+		if route.Host != host || !openAPIMethods[route.Method] {
+			continue
+		}
+		path, params := openAPIPath(route.Path)
+		op := &openAPIOperation{
+			Parameters: params,
+			Responses:  make(map[string]*openAPIResponse),
+		}
+
+		status := http.StatusOK
+		var response interface{}
+		if d := route.Doc; d != nil {
+			op.OperationID = d.OperationID
+			op.Summary = d.Summary
+			op.Description = d.Description
+			op.Tags = d.Tags
+			op.Deprecated = d.Deprecated
+			if d.ResponseStatus != 0 {
+				status = d.ResponseStatus
+			}
+			response = d.Response
+			if d.Request != nil {
+				switch route.Method {
+				case http.MethodGet, http.MethodHead, http.MethodDelete:
+					op.Parameters = append(op.Parameters, g.queryParameters(reflect.TypeOf(d.Request))...)
+				default:
+					op.RequestBody = &openAPIRequestBody{
+						Required: true,
+						Content:  jsonContent(g.schema(reflect.TypeOf(d.Request))),
+					}
+				}
+			}
+		}
+
+		resp := &openAPIResponse{Description: http.StatusText(status)}
+		if response != nil {
+			resp.Content = jsonContent(g.schema(reflect.TypeOf(response)))
+		}
+		op.Responses[strconv.Itoa(status)] = resp
+
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = make(map[string]*openAPIOperation)
+		}
+		doc.Paths[path][strings.ToLower(route.Method)] = op
+	}
+
+	if len(g.schemas) > 0 {
+		doc.Components = &openAPIComponents{Schemas: g.schemas}
+	}
+	return doc
+}
+
+// OpenAPIHandler returns a handler serving the OpenAPI document of engine as
+// JSON, for the host pattern the request Host matches. The document is
+// generated on every request, so it includes routes registered after the
+// handler.
+//
+//	r.GET("/openapi.json", xgin.OpenAPIHandler(r, xgin.OpenAPIInfo{Title: "api", Version: "1.0"}))
+func OpenAPIHandler(engine *Engine, info OpenAPIInfo) HandlerFunc {
+	return func(c *Context) {
+		host := ""
+		if h := engine.routeTable().hostOf(c.Request.Host); h != nil {
+			host = h.pattern
+		}
+		c.JSON(http.StatusOK, engine.OpenAPI(host, info))
+	}
+}
+
+// openAPIPath converts /users/:id<int>/*filepath into /users/{id}/{filepath}
+// and returns the path parameters.
+func openAPIPath(fullPath string) (string, []*openAPIParameter) {
+	var params []*openAPIParameter
+	var b strings.Builder
+	path := fullPath
+	for {
+		wildcard, i, _ := findWildcard(path)
+		if i < 0 {
+			b.WriteString(path)
+			break
+		}
+		b.WriteString(path[:i])
+		path = path[i+len(wildcard):]
+
+		name, constraint := splitWildcard(wildcard, fullPath)
+		name = name[1:]
+		b.WriteString("{" + name + "}")
+		params = append(params, &openAPIParameter{
+			Name:     name,
+			In:       "path",
+			Required: true,
+			Schema:   constraintSchema(constraint),
+		})
+	}
+	return b.String(), params
+}
+
+func constraintSchema(constraint string) *openAPISchema {
+	switch {
+	case constraint == "int":
+		return &openAPISchema{Type: "integer", Format: "int64"}
+	case constraint == "uint":
+		zero := 0.0
+		return &openAPISchema{Type: "integer", Format: "int64", Minimum: &zero}
+	case constraint == "uuid":
+		return &openAPISchema{Type: "string", Format: "uuid"}
+	case constraint == "alpha":
+		return &openAPISchema{Type: "string", Pattern: "^[A-Za-z]+$"}
+	case strings.HasPrefix(constraint, "re:"):
+		return &openAPISchema{Type: "string", Pattern: "^(?:" + constraint[len("re:"):] + ")$"}
+	}
+	return &openAPISchema{Type: "string"}
+}
+
+func jsonContent(schema *openAPISchema) map[string]*openAPIMediaType {
+	return map[string]*openAPIMediaType{"application/json": {Schema: schema}}
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// openAPIMethods are the operations a Path Item Object can hold.
+var openAPIMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodPut:     true,
+	http.MethodPost:    true,
+	http.MethodDelete:  true,
+	http.MethodOptions: true,
+	http.MethodHead:    true,
+	http.MethodPatch:   true,
+	http.MethodTrace:   true,
+}
+
+// schemaGenerator 把具名struct放到components.schemas中, 用$ref引用, 递归类型也能处理
+type schemaGenerator struct {
+	schemas map[string]*openAPISchema
+	names   map[reflect.Type]string
+}
+
+func (g *schemaGenerator) schema(t reflect.Type) *openAPISchema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return &openAPISchema{Type: "string", Format: "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &openAPISchema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32:
+		return &openAPISchema{Type: "integer", Format: "int32"}
+	case reflect.Int64:
+		return &openAPISchema{Type: "integer", Format: "int64"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		zero := 0.0
+		return &openAPISchema{Type: "integer", Minimum: &zero}
+	case reflect.Float32:
+		return &openAPISchema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &openAPISchema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &openAPISchema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			// []byte 被encoding/json编码为base64
+			return &openAPISchema{Type: "string", Format: "byte"}
+		}
+		return &openAPISchema{Type: "array", Items: g.schema(t.Elem())}
+	case reflect.Map:
+		return &openAPISchema{Type: "object", AdditionalProperties: g.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		name, ok := g.names[t]
+		if !ok {
+			name = g.schemaName(t)
+			g.names[t] = name
+			g.schemas[name] = nil // 先占位, 防止递归
+			g.schemas[name] = g.structSchema(t)
+		}
+		return &openAPISchema{Ref: "#/components/schemas/" + name}
+	}
+	// interface{} 等任意类型
+	return &openAPISchema{}
+}
+
+// schemaName returns the type name, qualified by the package path if another
+// package's type already took it, ie. "example.com.billing.User".
+func (g *schemaGenerator) schemaName(t reflect.Type) string {
+	name := t.Name()
+	if _, taken := g.schemas[name]; !taken {
+		return name
+	}
+	// components的key只能包含 a-zA-Z0-9.-_
+	qualified := strings.Map(func(r rune) rune {
+		if r == '/' {
+			return '.'
+		}
+		if r == '.' || r == '-' || r == '_' || r < 0x80 && (r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, t.PkgPath()) + "." + name
+	// 包路径也相同, 只可能是函数内定义的同名类型
+	candidate := qualified
+	for i := 2; ; i++ {
+		if _, taken := g.schemas[candidate]; !taken {
+			return candidate
+		}
+		candidate = qualified + "_" + strconv.Itoa(i)
+	}
+}
+
+func (g *schemaGenerator) structSchema(t reflect.Type) *openAPISchema {
+	s := &openAPISchema{Type: "object", Properties: make(map[string]*openAPISchema)}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" && !f.Anonymous {
+			continue
+		}
+		name, omitempty, skip := fieldName(f, "json")
+		if skip {
+			continue
+		}
+		// 匿名嵌入的struct, 字段提升到外层
+		if f.Anonymous && f.Tag.Get("json") == "" {
+			ft := f.Type
+			for ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				embedded := g.structSchema(ft)
+				for k, v := range embedded.Properties {
+					s.Properties[k] = v
+				}
+				s.Required = append(s.Required, embedded.Required...)
+				continue
+			}
+		}
+		s.Properties[name] = g.schema(f.Type)
+		if !omitempty && isRequired(f) {
+			s.Required = append(s.Required, name)
+		}
+	}
+	return s
+}
+
+func (g *schemaGenerator) queryParameters(t reflect.Type) []*openAPIParameter {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	var params []*openAPIParameter
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		name, _, skip := fieldName(f, "form")
+		if skip {
+			continue
+		}
+		params = append(params, &openAPIParameter{
+			Name:     name,
+			In:       "query",
+			Required: isRequired(f),
+			Schema:   g.schema(f.Type),
+		})
+	}
+	return params
+}
+
+// fieldName returns the name of the field in the given tag, ie. `json:"name,omitempty"`.
+func fieldName(f reflect.StructField, tag string) (name string, omitempty, skip bool) {
+	value := f.Tag.Get(tag)
+	if value == "-" {
+		return "", false, true
+	}
+	parts := strings.Split(value, ",")
+	name = parts[0]
+	if name == "" {
+		name = f.Name
+	}
+	for _, opt := range parts[1:] {
+		if opt == "omitempty" {
+			omitempty = true
+		}
+	}
+	return name, omitempty, false
+}
+
+// isRequired 按gin的习惯, 有 binding:"required" 的字段为必填
+func isRequired(f reflect.StructField) bool {
+	for _, rule := range strings.Split(f.Tag.Get("binding"), ",") {
+		if rule == "required" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,26 @@
+package xgin
+
+import "testing"
+
+func TestOpenAPIMethods(t *testing.T) {
+	r := New()
+	r.Any("/any", func(c *Context) {})
+	r.Mount("/admin", New())
+
+	doc := r.OpenAPI("", OpenAPIInfo{Title: "test", Version: "1"})
+	for _, path := range []string{"/any", "/admin", "/admin/{mountpath}"} {
+		ops, ok := doc.Paths[path]
+		if !ok {
+			t.Errorf("path %s missing", path)
+			continue
+		}
+		if _, ok := ops["connect"]; ok {
+			t.Errorf("path %s has a connect operation", path)
+		}
+		for _, method := range []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"} {
+			if ops[method] == nil {
+				t.Errorf("path %s has no %s operation", path, method)
+			}
+		}
+	}
+}
@@ -0,0 +1,52 @@
+// Package pprof serves the net/http/pprof profiles and the expvar
+// variables from a xgin router, so a live service can be profiled
+// without a second listener.
+package pprof
+
+import (
+	"expvar"
+	"net/http/pprof"
+
+	"srcrd/xgin"
+)
+
+const (
+	// DefaultPrefix url prefix of pprof
+	DefaultPrefix = "/debug/pprof"
+)
+
+func getPrefix(prefix string) string {
+	if prefix == "" {
+		return DefaultPrefix
+	}
+	return prefix
+}
+
+// Register the standard HandlerFuncs from the net/http/pprof package with
+// the provided xgin.Engine. prefix is a URI prefix, DefaultPrefix is used if
+// it is empty.
+func Register(r *xgin.Engine, prefix string) {
+	RouteRegister(&r.RouterGroup, prefix)
+}
+
+// RouteRegister the standard HandlerFuncs from the net/http/pprof package with
+// the provided xgin.RouterGroup. prefix is a URI prefix, DefaultPrefix is used
+// if it is empty. The expvar variables are served at prefix + "/vars".
+func RouteRegister(rg *xgin.RouterGroup, prefix string) {
+	prefixRouter := rg.Group(getPrefix(prefix))
+	{
+		prefixRouter.GET("/", xgin.WrapF(pprof.Index))
+		prefixRouter.GET("/cmdline", xgin.WrapF(pprof.Cmdline))
+		prefixRouter.GET("/profile", xgin.WrapF(pprof.Profile))
+		prefixRouter.POST("/symbol", xgin.WrapF(pprof.Symbol))
+		prefixRouter.GET("/symbol", xgin.WrapF(pprof.Symbol))
+		prefixRouter.GET("/trace", xgin.WrapF(pprof.Trace))
+		prefixRouter.GET("/allocs", xgin.WrapH(pprof.Handler("allocs")))
+		prefixRouter.GET("/block", xgin.WrapH(pprof.Handler("block")))
+		prefixRouter.GET("/goroutine", xgin.WrapH(pprof.Handler("goroutine")))
+		prefixRouter.GET("/heap", xgin.WrapH(pprof.Handler("heap")))
+		prefixRouter.GET("/mutex", xgin.WrapH(pprof.Handler("mutex")))
+		prefixRouter.GET("/threadcreate", xgin.WrapH(pprof.Handler("threadcreate")))
+		prefixRouter.GET("/vars", xgin.WrapH(expvar.Handler()))
+	}
+}
@@ -0,0 +1,185 @@
+package xgin
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// ProxyOptions defines the options of the Proxy handler.
+type ProxyOptions struct {
+	// Upstreams are more targets, requests are balanced round-robin across
+	// the target and the upstreams.
+	Upstreams []string
+
+	// PathParam is the catch-all param holding the path forwarded upstream.
+	// Defaults to the catch-all of the route, ie. "path" for "/api/*path",
+	// a route without catch-all forwards the request path unchanged.
+	PathParam string
+
+	// PreserveHost forwards the Host header of the request instead of the
+	// host of the upstream.
+	PreserveHost bool
+
+	// MaxFails consecutive errors mark an upstream down for FailTimeout,
+	// it is skipped while another one is up. Default 3 and 10s.
+	MaxFails    int
+	FailTimeout time.Duration
+
+	// Transport performs the upstream requests, http.DefaultTransport if nil.
+	Transport http.RoundTripper
+
+	// FlushInterval is passed to httputil.ReverseProxy, a negative value
+	// flushes after every write.
+	FlushInterval time.Duration
+
+	// ModifyResponse, if set, is called with the upstream response, an
+	// error is handled as an upstream error.
+	ModifyResponse func(*http.Response) error
+}
+
+type upstream struct {
+	target    *url.URL
+	fails     int32
+	downUntil int64 // unix nano
+}
+
+// Proxy returns a handler forwarding requests to target, ie. "http://10.0.0.1:8080/v1".
+// Request and response bodies are streamed. The path is target's path joined with
+// the catch-all param of the route, and X-Forwarded-For, X-Forwarded-Host and
+// X-Forwarded-Proto are set. An upstream that can't be reached is answered with
+// 502 Bad Gateway and the error is added to c.Errors.
+//
+//	r.Any("/api/*path", xgin.Proxy("http://10.0.0.1:8080/v1", xgin.ProxyOptions{
+//		Upstreams: []string{"http://10.0.0.2:8080/v1"},
+//	}))
+func Proxy(target string, opts ProxyOptions) HandlerFunc {
+	if opts.MaxFails <= 0 {
+		opts.MaxFails = 3
+	}
+	if opts.FailTimeout <= 0 {
+		opts.FailTimeout = 10 * time.Second
+	}
+	if opts.Transport == nil {
+		opts.Transport = http.DefaultTransport
+	}
+
+	var upstreams []*upstream
+	for _, t := range append([]string{target}, opts.Upstreams...) {
+		u, err := url.Parse(t)
+		if err != nil {
+			panic(err)
+		}
+		assert1(u.Scheme != "" && u.Host != "", "invalid proxy target '"+t+"'")
+		upstreams = append(upstreams, &upstream{target: u})
+	}
+
+	var next uint32
+	pick := func() *upstream {
+		n := atomic.AddUint32(&next, 1)
+		now := time.Now().UnixNano()
+		for i := 0; i < len(upstreams); i++ {
+			u := upstreams[(int(n)+i)%len(upstreams)]
+			if atomic.LoadInt64(&u.downUntil) <= now {
+				return u
+			}
+		}
+		// 全部down时仍然轮询, 让请求去探测是否恢复
+		return upstreams[int(n)%len(upstreams)]
+	}
+
+	return func(c *Context) {
+		u := pick()
+
+		path := c.Request.URL.Path
+		rawPath := c.Request.URL.RawPath
+		if name := proxyPathParam(c, opts.PathParam); name != "" {
+			path = c.Param(name)
+			rawPath = ""
+		}
+
+		req := c.Request.Clone(c.Request.Context())
+		outURL := *req.URL
+		outURL.Scheme = u.target.Scheme
+		outURL.Host = u.target.Host
+		outURL.Path = singleJoiningSlash(u.target.Path, path)
+		outURL.RawPath = ""
+		if rawPath != "" && u.target.RawPath == "" {
+			outURL.RawPath = singleJoiningSlash(u.target.Path, rawPath)
+		}
+		if u.target.RawQuery != "" {
+			if outURL.RawQuery == "" {
+				outURL.RawQuery = u.target.RawQuery
+			} else {
+				outURL.RawQuery = u.target.RawQuery + "&" + outURL.RawQuery
+			}
+		}
+		req.URL = &outURL
+
+		req.Header.Set("X-Forwarded-Host", c.Request.Host)
+		if c.Request.TLS != nil {
+			req.Header.Set("X-Forwarded-Proto", "https")
+		} else {
+			req.Header.Set("X-Forwarded-Proto", "http")
+		}
+		if !opts.PreserveHost {
+			req.Host = ""
+		}
+
+		rp := &httputil.ReverseProxy{
+			// URL已经改写好, X-Forwarded-For由ReverseProxy追加
+			Director:      func(*http.Request) {},
+			Transport:     opts.Transport,
+			FlushInterval: opts.FlushInterval,
+			ModifyResponse: func(resp *http.Response) error {
+				atomic.StoreInt32(&u.fails, 0)
+				if opts.ModifyResponse != nil {
+					return opts.ModifyResponse(resp)
+				}
+				return nil
+			},
+			ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+				// 客户端断开不算upstream的错误
+				if errors.Is(err, context.Canceled) && r.Context().Err() != nil {
+					c.Abort()
+					return
+				}
+				if atomic.AddInt32(&u.fails, 1) >= int32(opts.MaxFails) {
+					atomic.StoreInt64(&u.downUntil, time.Now().Add(opts.FailTimeout).UnixNano())
+					atomic.StoreInt32(&u.fails, 0)
+				}
+				c.Error(err).SetMeta(u.target.Host)
+				c.AbortWithStatus(http.StatusBadGateway)
+			},
+		}
+		rp.ServeHTTP(c.Writer, req)
+	}
+}
+
+// proxyPathParam returns the name of the catch-all param forwarded upstream.
+func proxyPathParam(c *Context, name string) string {
+	if name != "" {
+		return name
+	}
+	if i := strings.LastIndexByte(c.fullPath, '*'); i >= 0 {
+		return c.fullPath[i+1:]
+	}
+	return ""
+}
+
+func singleJoiningSlash(a, b string) string {
+	aslash := strings.HasSuffix(a, "/")
+	bslash := strings.HasPrefix(b, "/")
+	switch {
+	case aslash && bslash:
+		return a + b[1:]
+	case !aslash && !bslash:
+		return a + "/" + b
+	}
+	return a + b
+}
@@ -0,0 +1,173 @@
+package xgin
+
+import (
+	"hash/fnv"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	rateLimitShards      = 32
+	defaultBucketIdleTTL = 10 * time.Minute
+	// 每个shard每处理sweepInterval次请求，清理一次闲置的bucket
+	sweepInterval = 1024
+)
+
+// RateLimitKeyFunc returns the key a request is rate limited by.
+// Requests that share a key share a token bucket.
+type RateLimitKeyFunc func(c *Context) string
+
+// KeyByClientIP limits requests per client IP, see Context.ClientIP.
+func KeyByClientIP(c *Context) string {
+	return c.ClientIP()
+}
+
+// KeyByFullPath limits requests per matched route, ie. "/user/:id".
+func KeyByFullPath(c *Context) string {
+	return c.FullPath()
+}
+
+// KeyByHeader limits requests per value of the given request header,
+// for example an API key.
+func KeyByHeader(name string) RateLimitKeyFunc {
+	return func(c *Context) string {
+		return c.GetHeader(name)
+	}
+}
+
+// RateLimitConfig defines the config for the RateLimit middleware.
+type RateLimitConfig struct {
+	// Rate is the number of tokens added to a bucket per second.
+	Rate float64
+
+	// Burst is the bucket capacity, ie. the maximum number of requests
+	// a client may send at once.
+	Burst int
+
+	// KeyFunc selects the bucket of a request. Defaults to KeyByClientIP.
+	KeyFunc RateLimitKeyFunc
+
+	// IdleTTL is how long an untouched bucket is kept in memory.
+	// Defaults to 10 minutes.
+	IdleTTL time.Duration
+}
+
+// RateLimit returns a token bucket rate limiter middleware that allows
+// rate requests per second with bursts of up to burst requests per client IP.
+func RateLimit(rate float64, burst int) HandlerFunc {
+	return RateLimitWithConfig(RateLimitConfig{Rate: rate, Burst: burst})
+}
+
+// RateLimitWithConfig returns a token bucket rate limiter middleware with config.
+// Every response carries X-RateLimit-Limit, X-RateLimit-Remaining and
+// X-RateLimit-Reset headers, rejected requests are answered with
+// 429 Too Many Requests and a Retry-After header.
+func RateLimitWithConfig(conf RateLimitConfig) HandlerFunc {
+	if conf.Rate <= 0 || conf.Burst <= 0 {
+		panic("rate limit rate and burst must be positive")
+	}
+	if conf.KeyFunc == nil {
+		conf.KeyFunc = KeyByClientIP
+	}
+	if conf.IdleTTL <= 0 {
+		conf.IdleTTL = defaultBucketIdleTTL
+	}
+	store := newBucketStore(conf.Rate, float64(conf.Burst), conf.IdleTTL)
+	limit := strconv.Itoa(conf.Burst)
+
+	return func(c *Context) {
+		now := time.Now()
+		allowed, remaining, wait := store.take(conf.KeyFunc(c), now)
+
+		// 桶被填满所需的时间
+		full := time.Duration((float64(conf.Burst) - remaining) / conf.Rate * float64(time.Second))
+		c.Header("X-RateLimit-Limit", limit)
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(int(remaining)))
+		c.Header("X-RateLimit-Reset", strconv.FormatInt(now.Add(full).Unix(), 10))
+
+		if !allowed {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			c.AbortWithStatus(http.StatusTooManyRequests)
+			return
+		}
+		c.Next()
+	}
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+type bucketShard struct {
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+	ops     int
+}
+
+// bucketStore 按key的hash分片存放token bucket，减少锁竞争
+type bucketStore struct {
+	rate   float64
+	burst  float64
+	ttl    time.Duration
+	shards [rateLimitShards]bucketShard
+}
+
+func newBucketStore(rate, burst float64, ttl time.Duration) *bucketStore {
+	s := &bucketStore{rate: rate, burst: burst, ttl: ttl}
+	for i := range s.shards {
+		s.shards[i].buckets = make(map[string]*tokenBucket)
+	}
+	return s
+}
+
+func (s *bucketStore) shard(key string) *bucketShard {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return &s.shards[h.Sum32()%rateLimitShards]
+}
+
+// take removes a token from the bucket of key. It reports whether a token
+// was available, the tokens left and, if none was, how long until the next one.
+func (s *bucketStore) take(key string, now time.Time) (allowed bool, remaining float64, wait time.Duration) {
+	sh := s.shard(key)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	sh.ops++
+	if sh.ops >= sweepInterval {
+		sh.ops = 0
+		sh.sweep(now.Add(-s.ttl))
+	}
+
+	b := sh.buckets[key]
+	if b == nil {
+		b = &tokenBucket{tokens: s.burst, last: now}
+		sh.buckets[key] = b
+	}
+
+	// 按经过的时间补充token
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = math.Min(s.burst, b.tokens+elapsed.Seconds()*s.rate)
+		b.last = now
+	}
+
+	if b.tokens < 1 {
+		wait = time.Duration((1 - b.tokens) / s.rate * float64(time.Second))
+		return false, b.tokens, wait
+	}
+	b.tokens--
+	return true, b.tokens, 0
+}
+
+// sweep evicts the buckets not used since deadline.
+func (sh *bucketShard) sweep(deadline time.Time) {
+	for key, b := range sh.buckets {
+		if b.last.Before(deadline) {
+			delete(sh.buckets, key)
+		}
+	}
+}
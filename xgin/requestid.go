@@ -0,0 +1,155 @@
+package xgin
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+)
+
+const (
+	// HeaderRequestID is the header used to carry the request id.
+	HeaderRequestID = "X-Request-ID"
+	// HeaderTraceParent and HeaderTraceState are the W3C trace context headers.
+	// See https://www.w3.org/TR/trace-context/
+	HeaderTraceParent = "traceparent"
+	HeaderTraceState  = "tracestate"
+
+	// RequestIDKey is the key the request id is stored under in Context.Keys.
+	RequestIDKey = "_xgin/requestid"
+	// TraceContextKey is the key the TraceContext is stored under in Context.Keys.
+	TraceContextKey = "_xgin/tracecontext"
+
+	maxRequestIDLen = 128
+)
+
+// TraceContext is the parsed W3C trace context of a request.
+// ParentID is the span id of this hop: the incoming parent id is kept in
+// CallerID and a new one is generated, so downstream calls are children of it.
+type TraceContext struct {
+	Version  string
+	TraceID  string
+	ParentID string
+	CallerID string
+	Flags    string
+	State    string
+}
+
+// Sampled reports whether the caller recorded this trace.
+func (tc TraceContext) Sampled() bool {
+	b, err := hex.DecodeString(tc.Flags)
+	return err == nil && len(b) == 1 && b[0]&0x01 == 1
+}
+
+// TraceParent returns the traceparent header value to propagate downstream.
+func (tc TraceContext) TraceParent() string {
+	return tc.Version + "-" + tc.TraceID + "-" + tc.ParentID + "-" + tc.Flags
+}
+
+// RequestID returns a middleware that tags every request with an id and a
+// W3C trace context. The X-Request-ID header is reused when the client sent a
+// sane one, otherwise a random id is generated. A valid traceparent keeps its
+// trace id and gets a new parent id, an invalid or missing one starts a new trace.
+// Both are stored on the Context and echoed in the response headers.
+func RequestID() HandlerFunc {
+	return func(c *Context) {
+		id := c.requestHeader(HeaderRequestID)
+		if !validRequestID(id) {
+			id = randomHex(16)
+		}
+		c.Set(RequestIDKey, id)
+		c.Header(HeaderRequestID, id)
+
+		tc, ok := parseTraceParent(c.requestHeader(HeaderTraceParent))
+		if ok {
+			tc.State = c.requestHeader(HeaderTraceState)
+		} else {
+			// 开始一个新的trace，tracestate随无效的traceparent一起丢弃
+			tc = TraceContext{Version: "00", TraceID: randomHex(16), Flags: "00"}
+		}
+		tc.ParentID = randomHex(8)
+		c.Set(TraceContextKey, tc)
+		c.Header(HeaderTraceParent, tc.TraceParent())
+		c.Header(HeaderTraceState, tc.State)
+
+		c.Next()
+	}
+}
+
+// RequestID returns the id set by the RequestID middleware, or "".
+func (c *Context) RequestID() string {
+	if v, ok := c.Get(RequestIDKey); ok {
+		id, _ := v.(string)
+		return id
+	}
+	return ""
+}
+
+// TraceContext returns the trace context set by the RequestID middleware.
+func (c *Context) TraceContext() (TraceContext, bool) {
+	if v, ok := c.Get(TraceContextKey); ok {
+		tc, ok := v.(TraceContext)
+		return tc, ok
+	}
+	return TraceContext{}, false
+}
+
+// validRequestID 只接受长度有限的可打印ASCII，防止日志注入
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// parseTraceParent parses "version-traceid-parentid-flags", ie.
+// 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01
+func parseTraceParent(s string) (tc TraceContext, ok bool) {
+	parts := strings.Split(strings.TrimSpace(s), "-")
+	if len(parts) < 4 {
+		return tc, false
+	}
+	version, traceID, parentID, flags := parts[0], parts[1], parts[2], parts[3]
+	if !isLowerHex(version, 2) || version == "ff" {
+		return tc, false
+	}
+	// version 00 必须恰好4段，更高版本允许在后面追加字段
+	if version == "00" && len(parts) != 4 {
+		return tc, false
+	}
+	if !isLowerHex(traceID, 32) || isZeroHex(traceID) ||
+		!isLowerHex(parentID, 16) || isZeroHex(parentID) ||
+		!isLowerHex(flags, 2) {
+		return tc, false
+	}
+	return TraceContext{Version: "00", TraceID: traceID, CallerID: parentID, Flags: flags}, true
+}
+
+func isLowerHex(s string, n int) bool {
+	if len(s) != n {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		if !('0' <= ch && ch <= '9' || 'a' <= ch && ch <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func isZeroHex(s string) bool {
+	return strings.Trim(s, "0") == ""
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
@@ -1,22 +1,44 @@
 package xgin
 
 import (
+	"context"
 	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
 )
 
+var (
+	// regEnLetter matches english letters for http method name
+	regEnLetter = regexp.MustCompile("^[A-Z]+$")
+
+	// anyMethods for RouterGroup Any method
+	anyMethods = []string{
+		http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodHead, http.MethodOptions, http.MethodDelete, http.MethodConnect,
+		http.MethodTrace,
+	}
+)
+
+// IRouter defines all router handle interface includes single and group router.
+type IRouter interface {
+	IRoutes
+	Group(string, ...HandlerFunc) *RouterGroup
+}
+
 // IRoutes defines all router handle interface.
 type IRoutes interface {
 	Use(...HandlerFunc) IRoutes
 
-	// Handle(string, string, ...HandlerFunc) IRoutes
-	// Any(string, ...HandlerFunc) IRoutes
+	Handle(string, string, ...HandlerFunc) IRoutes
+	Any(string, ...HandlerFunc) IRoutes
 	GET(string, ...HandlerFunc) IRoutes
-	// POST(string, ...HandlerFunc) IRoutes
-	// DELETE(string, ...HandlerFunc) IRoutes
-	// PATCH(string, ...HandlerFunc) IRoutes
-	// PUT(string, ...HandlerFunc) IRoutes
-	// OPTIONS(string, ...HandlerFunc) IRoutes
-	// HEAD(string, ...HandlerFunc) IRoutes
+	POST(string, ...HandlerFunc) IRoutes
+	DELETE(string, ...HandlerFunc) IRoutes
+	PATCH(string, ...HandlerFunc) IRoutes
+	PUT(string, ...HandlerFunc) IRoutes
+	OPTIONS(string, ...HandlerFunc) IRoutes
+	HEAD(string, ...HandlerFunc) IRoutes
 
 	// StaticFile(string, string) IRoutes
 	// Static(string, string) IRoutes
@@ -30,21 +52,174 @@ type RouterGroup struct {
 	basePath string
 	engine   *Engine
 	root     bool
+	table    *routeTable // nil时注册到engine正在使用的路由表, 见Engine.NewRouteTable
+	host     string      // 见Engine.Host
+	doc      *RouteDoc
+}
+
+var _ IRouter = &RouterGroup{}
+
+// Group creates a new router group. You should add all the routes that have common middlewares or the same path prefix.
+// For example, all the routes that use a common middleware for authorization could be grouped.
+func (group *RouterGroup) Group(relativePath string, handlers ...HandlerFunc) *RouterGroup {
+	return &RouterGroup{
+		Handlers: group.combineHandlers(handlers),
+		basePath: group.calculateAbsolutePath(relativePath),
+		engine:   group.engine,
+		table:    group.table,
+		host:     group.host,
+	}
+}
+
+// BasePath returns the base path of router group.
+// For example, if v := router.Group("/rest/n/v1/api"), v.BasePath() is "/rest/n/v1/api".
+func (group *RouterGroup) BasePath() string {
+	return group.basePath
 }
 
 func (group *RouterGroup) handle(httpMethod, relativePath string, handlers HandlersChain) IRoutes {
 	absolutePath := group.calculateAbsolutePath(relativePath)
 	handlers = group.combineHandlers(handlers)
 	// 添加handlers
-	group.engine.addRoute(httpMethod, absolutePath, handlers)
+	table := group.table
+	if table == nil {
+		table = group.engine.routeTable()
+	}
+	table.addRoute(group.host, httpMethod, absolutePath, handlers)
+	if group.doc != nil {
+		table.addDoc(group.host, httpMethod, absolutePath, group.doc)
+	}
 	return group.returnObj()
 }
 
+// Doc returns a copy of the group whose routes carry doc, it is used to
+// generate the OpenAPI document, see OpenAPI.
+//
+//	r.Doc(xgin.RouteDoc{Summary: "Get a user", Response: User{}}).GET("/users/:id", getUser)
+func (group *RouterGroup) Doc(doc RouteDoc) *RouterGroup {
+	return &RouterGroup{
+		Handlers: group.Handlers,
+		basePath: group.basePath,
+		engine:   group.engine,
+		table:    group.table,
+		host:     group.host,
+		doc:      &doc,
+	}
+}
+
+// Handle registers a new request handle and middleware with the given path and method.
+// The last handler should be the real handler, the other ones should be middleware that can and should be shared among different routes.
+// See the example code in GitHub.
+//
+// For GET, POST, PUT, PATCH and DELETE requests the respective shortcut
+// functions can be used.
+//
+// This function is intended for bulk loading and to allow the usage of less
+// frequently used, non-standardized or custom methods (e.g. for internal
+// communication with a proxy).
+func (group *RouterGroup) Handle(httpMethod, relativePath string, handlers ...HandlerFunc) IRoutes {
+	if matches := regEnLetter.MatchString(httpMethod); !matches {
+		panic("http method " + httpMethod + " is not valid")
+	}
+	return group.handle(httpMethod, relativePath, handlers)
+}
+
+// POST is a shortcut for router.Handle("POST", path, handle).
+func (group *RouterGroup) POST(relativePath string, handlers ...HandlerFunc) IRoutes {
+	return group.handle(http.MethodPost, relativePath, handlers)
+}
+
 // GET is a shortcut for router.Handle("GET", path, handle).
 func (group *RouterGroup) GET(relativePath string, handlers ...HandlerFunc) IRoutes {
 	return group.handle(http.MethodGet, relativePath, handlers)
 }
 
+// DELETE is a shortcut for router.Handle("DELETE", path, handle).
+func (group *RouterGroup) DELETE(relativePath string, handlers ...HandlerFunc) IRoutes {
+	return group.handle(http.MethodDelete, relativePath, handlers)
+}
+
+// PATCH is a shortcut for router.Handle("PATCH", path, handle).
+func (group *RouterGroup) PATCH(relativePath string, handlers ...HandlerFunc) IRoutes {
+	return group.handle(http.MethodPatch, relativePath, handlers)
+}
+
+// PUT is a shortcut for router.Handle("PUT", path, handle).
+func (group *RouterGroup) PUT(relativePath string, handlers ...HandlerFunc) IRoutes {
+	return group.handle(http.MethodPut, relativePath, handlers)
+}
+
+// OPTIONS is a shortcut for router.Handle("OPTIONS", path, handle).
+func (group *RouterGroup) OPTIONS(relativePath string, handlers ...HandlerFunc) IRoutes {
+	return group.handle(http.MethodOptions, relativePath, handlers)
+}
+
+// HEAD is a shortcut for router.Handle("HEAD", path, handle).
+func (group *RouterGroup) HEAD(relativePath string, handlers ...HandlerFunc) IRoutes {
+	return group.handle(http.MethodHead, relativePath, handlers)
+}
+
+// Any registers a route that matches all the HTTP methods.
+// GET, POST, PUT, PATCH, HEAD, OPTIONS, DELETE, CONNECT, TRACE.
+func (group *RouterGroup) Any(relativePath string, handlers ...HandlerFunc) IRoutes {
+	for _, method := range anyMethods {
+		group.handle(method, relativePath, handlers)
+	}
+
+	return group.returnObj()
+}
+
+// mountParam is the catch-all param of the routes registered by Mount.
+const mountParam = "mountpath"
+
+// mountPrefixKey is the request context key of the path prefix stripped by
+// the Mounts, the mounted Engine prepends it to its redirects.
+type mountPrefixKey struct{}
+
+// mountPrefix returns the path prefix stripped from req by the Mounts.
+func mountPrefix(req *http.Request) string {
+	prefix, _ := req.Context().Value(mountPrefixKey{}).(string)
+	return prefix
+}
+
+// Mount serves all the requests under prefix, for any method, by h with the
+// prefix stripped from the path, like http.StripPrefix. h can be another
+// Engine, so separately built modules compose into one:
+//
+//	admin := xgin.New()
+//	admin.GET("/users", listUsers)
+//	r.Mount("/admin", admin) // GET /admin/users is GET /users on admin
+//
+// The group middleware runs before h. The trailing slash redirects of a
+// mounted Engine keep the prefix. Mount registers prefix and the catch-all
+// prefix + "/*mountpath", so it conflicts with other routes under prefix.
+func (group *RouterGroup) Mount(prefix string, h http.Handler) IRoutes {
+	absolutePrefix := strings.TrimSuffix(group.calculateAbsolutePath(prefix), "/")
+	handler := func(c *Context) {
+		path := c.Param(mountParam)
+		// 去掉的前缀, 路径被转义过时取注册的前缀
+		stripped := strings.TrimSuffix(c.Request.URL.Path, path)
+		if len(stripped)+len(path) != len(c.Request.URL.Path) {
+			stripped = absolutePrefix
+		}
+		if path == "" {
+			path = "/"
+		}
+		ctx := context.WithValue(c.Request.Context(), mountPrefixKey{}, mountPrefix(c.Request)+stripped)
+		r2 := c.Request.WithContext(ctx)
+		r2.URL = new(url.URL)
+		*r2.URL = *c.Request.URL
+		r2.URL.Path = path
+		r2.URL.RawPath = ""
+		h.ServeHTTP(c.Writer, r2)
+	}
+
+	if prefix = strings.TrimSuffix(prefix, "/"); prefix != "" {
+		group.Any(prefix, handler)
+	}
+	return group.Any(prefix+"/*"+mountParam, handler)
+}
+
 // Use adds middleware to the group, see example code in GitHub.
 func (group *RouterGroup) Use(middleware ...HandlerFunc) IRoutes {
 	group.Handlers = append(group.Handlers, middleware...) // 修改group.Handlers
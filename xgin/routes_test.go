@@ -0,0 +1,232 @@
+package xgin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func performRequest(r http.Handler, method, path string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestRouteMethods(t *testing.T) {
+	methods := []string{
+		http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodHead, http.MethodOptions,
+	}
+	for _, method := range methods {
+		t.Run(method, func(t *testing.T) {
+			r := New()
+			passed := false
+			r.Handle(method, "/test", func(c *Context) {
+				passed = true
+			})
+
+			w := performRequest(r, method, "/test")
+			if !passed || w.Code != http.StatusOK {
+				t.Errorf("%s /test: passed=%v code=%d", method, passed, w.Code)
+			}
+			// 其他方法的树中没有这个路由
+			other := http.MethodGet
+			if method == http.MethodGet {
+				other = http.MethodPost
+			}
+			if w := performRequest(r, other, "/test"); w.Code != http.StatusNotFound {
+				t.Errorf("%s /test code = %d, want 404", other, w.Code)
+			}
+		})
+	}
+}
+
+func TestRouteAnyAndGroup(t *testing.T) {
+	r := New()
+	var route string
+	v1 := r.Group("/v1", func(c *Context) {
+		c.Header("X-Group", "v1")
+	})
+	v1.Any("/users/:id", func(c *Context) {
+		route = c.FullPath() + " " + c.Param("id")
+	})
+	if got := v1.BasePath(); got != "/v1" {
+		t.Errorf("BasePath() = %q, want /v1", got)
+	}
+
+	for _, method := range anyMethods {
+		route = ""
+		w := performRequest(r, method, "/v1/users/42")
+		if w.Code != http.StatusOK || route != "/v1/users/:id 42" {
+			t.Errorf("%s /v1/users/42: code=%d route=%q", method, w.Code, route)
+		}
+		if w.Header().Get("X-Group") != "v1" {
+			t.Errorf("%s /v1/users/42: group middleware not run", method)
+		}
+	}
+}
+
+func TestRouteNotFound(t *testing.T) {
+	r := New()
+	r.GET("/a", func(c *Context) {})
+
+	w := performRequest(r, http.MethodGet, "/b")
+	if w.Code != http.StatusNotFound || w.Body.String() != "404 page not found" {
+		t.Errorf("GET /b = %d %q, want 404", w.Code, w.Body.String())
+	}
+}
+
+func TestRouteRedirectTrailingSlash(t *testing.T) {
+	r := New()
+	r.GET("/path", func(c *Context) {})
+	r.GET("/path2/", func(c *Context) {})
+	r.POST("/path3", func(c *Context) {})
+	r.PUT("/path4/", func(c *Context) {})
+	r.GET("/static/*filepath", func(c *Context) {})
+
+	tests := []struct {
+		method   string
+		path     string
+		code     int
+		location string
+	}{
+		{http.MethodGet, "/path/", http.StatusMovedPermanently, "/path"},
+		{http.MethodGet, "/path/?a=1", http.StatusMovedPermanently, "/path?a=1"},
+		{http.MethodGet, "/path2", http.StatusMovedPermanently, "/path2/"},
+		{http.MethodPost, "/path3/", http.StatusTemporaryRedirect, "/path3"},
+		{http.MethodPut, "/path4", http.StatusTemporaryRedirect, "/path4/"},
+		{http.MethodGet, "/static", http.StatusMovedPermanently, "/static/"},
+		{http.MethodGet, "/path", http.StatusOK, ""},
+		{http.MethodGet, "/path2/", http.StatusOK, ""},
+		{http.MethodGet, "/", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		w := performRequest(r, tt.method, tt.path)
+		if w.Code != tt.code || w.Header().Get("Location") != tt.location {
+			t.Errorf("%s %s = %d %q, want %d %q", tt.method, tt.path, w.Code, w.Header().Get("Location"), tt.code, tt.location)
+		}
+	}
+
+	r.RedirectTrailingSlash = false
+	if w := performRequest(r, http.MethodGet, "/path/"); w.Code != http.StatusNotFound {
+		t.Errorf("GET /path/ without RedirectTrailingSlash = %d, want 404", w.Code)
+	}
+}
+
+func TestRouteMount(t *testing.T) {
+	var route string
+	record := func(c *Context) {
+		route = c.FullPath() + " " + c.Request.URL.Path
+	}
+
+	admin := New()
+	admin.GET("/", record)
+	admin.GET("/users", record)
+	admin.GET("/users/:id", record)
+	admin.GET("/groups/", record)
+
+	api := New()
+	api.GET("/items/", record)
+	admin.Mount("/api", api)
+
+	r := New()
+	r.Mount("/admin", admin)
+
+	tests := []struct {
+		path     string
+		code     int
+		route    string
+		location string
+	}{
+		{"/admin", http.StatusOK, "/ /", ""},
+		{"/admin/", http.StatusOK, "/ /", ""},
+		{"/admin/users", http.StatusOK, "/users /users", ""},
+		{"/admin/users/42", http.StatusOK, "/users/:id /users/42", ""},
+		{"/admin/users/?a=1", http.StatusMovedPermanently, "", "/admin/users?a=1"},
+		{"/admin/groups", http.StatusMovedPermanently, "", "/admin/groups/"},
+		{"/admin/api/items/", http.StatusOK, "/items/ /items/", ""},
+		{"/admin/api/items", http.StatusMovedPermanently, "", "/admin/api/items/"},
+		{"/admin/missing", http.StatusNotFound, "", ""},
+	}
+	for _, tt := range tests {
+		route = ""
+		w := performRequest(r, http.MethodGet, tt.path)
+		if w.Code != tt.code || route != tt.route || w.Header().Get("Location") != tt.location {
+			t.Errorf("GET %s = %d %q %q, want %d %q %q", tt.path, w.Code, route, w.Header().Get("Location"), tt.code, tt.route, tt.location)
+		}
+	}
+}
+
+func TestRouteMiddlewareOnNotFoundAndRedirect(t *testing.T) {
+	r := New()
+	var status int
+	r.Use(func(c *Context) {
+		c.Header("X-Request-Id", "1")
+		if c.GetHeader("X-Deny") != "" {
+			c.AbortWithStatus(http.StatusForbidden)
+			return
+		}
+		c.Next()
+		status = c.Writer.Status()
+	})
+	r.GET("/path", func(c *Context) {})
+
+	tests := []struct {
+		path   string
+		deny   bool
+		code   int
+		status int
+	}{
+		{"/path/", false, http.StatusMovedPermanently, http.StatusMovedPermanently},
+		{"/missing", false, http.StatusNotFound, http.StatusNotFound},
+		{"/path/", true, http.StatusForbidden, 0},
+		{"/missing", true, http.StatusForbidden, 0},
+	}
+	for _, tt := range tests {
+		status = 0
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		if tt.deny {
+			req.Header.Set("X-Deny", "1")
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tt.code || status != tt.status || w.Header().Get("X-Request-Id") != "1" {
+			t.Errorf("GET %s deny=%v = %d status=%d X-Request-Id=%q, want %d status=%d", tt.path, tt.deny, w.Code, status, w.Header().Get("X-Request-Id"), tt.code, tt.status)
+		}
+		if tt.deny && (w.Header().Get("Location") != "" || w.Body.Len() != 0) {
+			t.Errorf("GET %s denied: Location=%q body=%q, want none", tt.path, w.Header().Get("Location"), w.Body.String())
+		}
+	}
+}
+
+func TestRouteConstraintParams(t *testing.T) {
+	r := New()
+	var id int
+	var n uint
+	r.GET("/users/:id<int>", func(c *Context) {
+		var err error
+		if id, err = c.ParamInt("id"); err != nil {
+			t.Errorf("ParamInt: %v", err)
+		}
+	})
+	r.GET("/orders/:n<uint>", func(c *Context) {
+		var err error
+		if n, err = c.ParamUint("n"); err != nil {
+			t.Errorf("ParamUint: %v", err)
+		}
+	})
+
+	if w := performRequest(r, http.MethodGet, "/users/-42"); w.Code != http.StatusOK || id != -42 {
+		t.Errorf("GET /users/-42 = %d, id=%d, want 200 -42", w.Code, id)
+	}
+	if w := performRequest(r, http.MethodGet, "/orders/42"); w.Code != http.StatusOK || n != 42 {
+		t.Errorf("GET /orders/42 = %d, n=%d, want 200 42", w.Code, n)
+	}
+	// 约束不满足是404, 不会重定向
+	for _, path := range []string{"/users/x", "/users/x/", "/orders/-1"} {
+		if w := performRequest(r, http.MethodGet, path); w.Code != http.StatusNotFound {
+			t.Errorf("GET %s = %d, want 404", path, w.Code)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package xgin
+
+// routeTable holds all the routes of an Engine, it is replaced as a whole by
+// Engine.SwapRoutes.
+type routeTable struct {
+	trees     methodTrees
+	hosts     []*hostTree // 按Host注册的路由, 见Engine.Host
+	maxParams uint16
+	docs      map[string]*RouteDoc // key: host + " " + method + " " + path
+}
+
+func (t *routeTable) addRoute(host, method, path string, handlers HandlersChain) {
+	assert1(path[0] == '/', "path must begin with '/'")
+	assert1(method != "", "HTTP method can not be empty")
+	assert1(len(handlers) > 0, "there must be at least one handler")
+
+	trees := &t.trees
+	if host != "" {
+		trees = t.hostTrees(host)
+	}
+	root := trees.get(method)
+	if root == nil {
+		root = new(node)
+		root.fullPath = "/"
+		*trees = append(*trees, methodTree{method: method, root: root})
+	}
+	root.addRoute(path, handlers)
+
+	// Update maxParams
+	if paramsCount := countParams(path); paramsCount > t.maxParams {
+		t.maxParams = paramsCount
+	}
+}
+
+func (t *routeTable) addDoc(host, method, path string, doc *RouteDoc) {
+	if t.docs == nil {
+		t.docs = make(map[string]*RouteDoc)
+	}
+	t.docs[host+" "+method+" "+path] = doc
+}
+
+func (t *routeTable) routesInfo() (routes RoutesInfo) {
+	for _, tree := range t.trees {
+		routes = iterate("", tree.method, routes, tree.root)
+	}
+	for _, h := range t.hosts {
+		for _, tree := range h.trees {
+			routes = iterate(h.pattern, tree.method, routes, tree.root)
+		}
+	}
+	for i := range routes {
+		routes[i].Doc = t.docs[routes[i].Host+" "+routes[i].Method+" "+routes[i].Path]
+	}
+	return routes
+}
+
+// RouteTable is a set of routes built apart from the routes an Engine is
+// serving, it is registered like on the Engine and installed by SwapRoutes.
+type RouteTable struct {
+	RouterGroup
+}
+
+// NewRouteTable returns an empty route table for engine. Its routes start
+// with the engine middleware registered so far.
+//
+//	t := r.NewRouteTable()
+//	t.GET("/", index)
+//	if flags.NewCheckout {
+//		t.POST("/checkout", newCheckout)
+//	}
+//	r.SwapRoutes(t)
+func (engine *Engine) NewRouteTable() *RouteTable {
+	return &RouteTable{
+		RouterGroup: RouterGroup{
+			Handlers: engine.combineHandlers(nil),
+			basePath: engine.basePath,
+			engine:   engine,
+			table:    &routeTable{},
+		},
+	}
+}
+
+// Host is Engine.Host for the routes of the table.
+func (t *RouteTable) Host(pattern string, handlers ...HandlerFunc) *RouterGroup {
+	return t.hostGroup(pattern, handlers)
+}
+
+// Routes returns the routes of the table, see Engine.Routes.
+func (t *RouteTable) Routes() RoutesInfo {
+	return t.table.routesInfo()
+}
+
+// SwapRoutes atomically replaces the routes served by engine with the table
+// and returns the previous ones, ie. to swap them back. Requests in flight
+// finish on the handlers they matched, new ones are routed by the table.
+// The table must not be modified once swapped in, the routes registered on
+// the engine afterwards are added to it.
+func (engine *Engine) SwapRoutes(t *RouteTable) (old *RouteTable) {
+	assert1(t.engine == engine, "route table belongs to another engine")
+	engine.swapMu.Lock()
+	prev := engine.routes.Load().(*routeTable)
+	engine.routes.Store(t.table)
+	engine.swapMu.Unlock()
+	return &RouteTable{
+		RouterGroup: RouterGroup{
+			Handlers: engine.combineHandlers(nil),
+			basePath: engine.basePath,
+			engine:   engine,
+			table:    prev,
+		},
+	}
+}
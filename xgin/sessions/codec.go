@@ -0,0 +1,132 @@
+package sessions
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidCookie is returned when a cookie is malformed or its
+	// signature doesn't match any key.
+	ErrInvalidCookie = errors.New("sessions: invalid cookie")
+	// ErrExpiredCookie is returned when a cookie is older than its MaxAge.
+	ErrExpiredCookie = errors.New("sessions: expired cookie")
+)
+
+// KeyPair is a key for signing the cookie with HMAC-SHA256 and an optional
+// key for encrypting it with AES-GCM.
+type KeyPair struct {
+	// HashKey is required, it should be 32 or 64 random bytes.
+	HashKey []byte
+	// BlockKey enables encryption, it must be 16, 24 or 32 bytes to select
+	// AES-128, AES-192 or AES-256.
+	BlockKey []byte
+
+	aead cipher.AEAD
+}
+
+// codec signs and encrypts cookie values. The first key pair encodes, all
+// of them are tried to decode, so keys can be rotated by prepending the new one.
+type codec struct {
+	keys []KeyPair
+}
+
+func newCodec(keys []KeyPair) *codec {
+	if len(keys) == 0 {
+		panic("sessions: at least one key pair is required")
+	}
+	cd := &codec{keys: make([]KeyPair, len(keys))}
+	for i, k := range keys {
+		if len(k.HashKey) == 0 {
+			panic("sessions: hash key is required")
+		}
+		if k.BlockKey != nil {
+			block, err := aes.NewCipher(k.BlockKey)
+			if err != nil {
+				panic("sessions: " + err.Error())
+			}
+			if k.aead, err = cipher.NewGCM(block); err != nil {
+				panic("sessions: " + err.Error())
+			}
+		}
+		cd.keys[i] = k
+	}
+	return cd
+}
+
+// encode returns base64(timestamp|payload) + "." + base64(mac), payload is
+// nonce|ciphertext if the key pair has a block key. name is authenticated,
+// so a value can't be moved to another cookie.
+func (cd *codec) encode(name string, data []byte) (string, error) {
+	k := cd.keys[0]
+	if k.aead != nil {
+		nonce := make([]byte, k.aead.NonceSize())
+		if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+			return "", err
+		}
+		data = k.aead.Seal(nonce, nonce, data, []byte(name))
+	}
+	b := make([]byte, 8+len(data))
+	binary.BigEndian.PutUint64(b, uint64(time.Now().Unix()))
+	copy(b[8:], data)
+
+	value := base64.RawURLEncoding.EncodeToString(b)
+	mac := base64.RawURLEncoding.EncodeToString(computeMAC(k.HashKey, name, value))
+	return value + "." + mac, nil
+}
+
+// decode verifies and decrypts value, maxAge 0 means no expiration.
+func (cd *codec) decode(name, value string, maxAge int) ([]byte, error) {
+	i := strings.LastIndexByte(value, '.')
+	if i < 0 {
+		return nil, ErrInvalidCookie
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(value[i+1:])
+	if err != nil {
+		return nil, ErrInvalidCookie
+	}
+	value = value[:i]
+
+	for _, k := range cd.keys {
+		if !hmac.Equal(mac, computeMAC(k.HashKey, name, value)) {
+			continue
+		}
+		b, err := base64.RawURLEncoding.DecodeString(value)
+		if err != nil || len(b) < 8 {
+			return nil, ErrInvalidCookie
+		}
+		ts := int64(binary.BigEndian.Uint64(b))
+		if maxAge > 0 && ts+int64(maxAge) < time.Now().Unix() {
+			return nil, ErrExpiredCookie
+		}
+		data := b[8:]
+		if k.aead != nil {
+			n := k.aead.NonceSize()
+			if len(data) < n {
+				return nil, ErrInvalidCookie
+			}
+			if data, err = k.aead.Open(nil, data[:n], data[n:], []byte(name)); err != nil {
+				return nil, ErrInvalidCookie
+			}
+		}
+		return data, nil
+	}
+	return nil, ErrInvalidCookie
+}
+
+func computeMAC(key []byte, name, value string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(name))
+	h.Write([]byte{'|'})
+	h.Write([]byte(value))
+	return h.Sum(nil)
+}
@@ -0,0 +1,171 @@
+package sessions
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"srcrd/xgin"
+)
+
+func TestCodecRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		key  KeyPair
+	}{
+		{"signed", KeyPair{HashKey: []byte("hash-key-0123456789abcdef0123456")}},
+		{"aes-128", KeyPair{HashKey: []byte("hash-key"), BlockKey: bytes.Repeat([]byte("k"), 16)}},
+		{"aes-256", KeyPair{HashKey: []byte("hash-key"), BlockKey: bytes.Repeat([]byte("k"), 32)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cd := newCodec([]KeyPair{tt.key})
+			value, err := cd.encode("session", []byte("secret payload"))
+			if err != nil {
+				t.Fatalf("encode: %v", err)
+			}
+			// 加密后cookie里不能出现明文
+			b, _ := base64.RawURLEncoding.DecodeString(value[:strings.IndexByte(value, '.')])
+			if encrypted := !bytes.Contains(b, []byte("secret payload")); encrypted != (tt.key.BlockKey != nil) {
+				t.Errorf("payload encrypted = %v, want %v", encrypted, tt.key.BlockKey != nil)
+			}
+			data, err := cd.decode("session", value, 0)
+			if err != nil || string(data) != "secret payload" {
+				t.Errorf("decode = %q, %v, want %q", data, err, "secret payload")
+			}
+		})
+	}
+}
+
+func TestCodecKeyRotation(t *testing.T) {
+	oldKey := KeyPair{HashKey: []byte("old-hash"), BlockKey: bytes.Repeat([]byte("o"), 16)}
+	newKey := KeyPair{HashKey: []byte("new-hash"), BlockKey: bytes.Repeat([]byte("n"), 16)}
+
+	oldValue, err := newCodec([]KeyPair{oldKey}).encode("session", []byte("v"))
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+
+	rotated := newCodec([]KeyPair{newKey, oldKey})
+	if data, err := rotated.decode("session", oldValue, 0); err != nil || string(data) != "v" {
+		t.Errorf("decode with old key = %q, %v, want %q", data, err, "v")
+	}
+
+	newValue, err := rotated.encode("session", []byte("v"))
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	// 新值必须用第一个key编码, 只有新key能解开
+	if _, err := newCodec([]KeyPair{oldKey}).decode("session", newValue, 0); err != ErrInvalidCookie {
+		t.Errorf("decode new value with old key error = %v, want ErrInvalidCookie", err)
+	}
+	if data, err := newCodec([]KeyPair{newKey}).decode("session", newValue, 0); err != nil || string(data) != "v" {
+		t.Errorf("decode new value with new key = %q, %v, want %q", data, err, "v")
+	}
+}
+
+func TestCodecRejects(t *testing.T) {
+	cd := newCodec([]KeyPair{{HashKey: []byte("hash-key"), BlockKey: bytes.Repeat([]byte("k"), 16)}})
+	value, err := cd.encode("session", []byte("v"))
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	i := strings.LastIndexByte(value, '.')
+
+	// 改掉最后一个字符
+	flip := func(s string) string {
+		if s[len(s)-1] == 'A' {
+			return s[:len(s)-1] + "B"
+		}
+		return s[:len(s)-1] + "A"
+	}
+
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{"tampered mac", flip(value)},
+		{"tampered value", flip(value[:i]) + value[i:]},
+		{"no mac", value[:i]},
+		{"bad base64", value + "!"},
+		{"empty", ""},
+	}
+	for _, tt := range tests {
+		if _, err := cd.decode("session", tt.value, 0); err != ErrInvalidCookie {
+			t.Errorf("%s: decode error = %v, want ErrInvalidCookie", tt.name, err)
+		}
+	}
+
+	// cookie的name参与签名, 不能换到其他cookie下使用
+	if _, err := cd.decode("other", value, 0); err != ErrInvalidCookie {
+		t.Errorf("decode under another name error = %v, want ErrInvalidCookie", err)
+	}
+}
+
+func TestCodecMaxAge(t *testing.T) {
+	key := KeyPair{HashKey: []byte("hash-key")}
+	cd := newCodec([]KeyPair{key})
+
+	// 构造一个一小时前签发的cookie
+	b := make([]byte, 9)
+	binary.BigEndian.PutUint64(b, uint64(time.Now().Add(-time.Hour).Unix()))
+	b[8] = 'v'
+	value := base64.RawURLEncoding.EncodeToString(b)
+	value += "." + base64.RawURLEncoding.EncodeToString(computeMAC(key.HashKey, "session", value))
+
+	if _, err := cd.decode("session", value, 60); err != ErrExpiredCookie {
+		t.Errorf("decode with MaxAge 60 error = %v, want ErrExpiredCookie", err)
+	}
+	if data, err := cd.decode("session", value, 7200); err != nil || string(data) != "v" {
+		t.Errorf("decode with MaxAge 7200 = %q, %v, want %q", data, err, "v")
+	}
+	if data, err := cd.decode("session", value, 0); err != nil || string(data) != "v" {
+		t.Errorf("decode with MaxAge 0 = %q, %v, want %q", data, err, "v")
+	}
+}
+
+func TestSessionFlashes(t *testing.T) {
+	r := xgin.New()
+	r.Use(Sessions(Config{Keys: testKeys}))
+	r.GET("/add", func(c *xgin.Context) {
+		s := Default(c)
+		s.AddFlash("hello")
+		s.AddFlash("world")
+		s.Save()
+	})
+	var flashes []interface{}
+	r.GET("/read", func(c *xgin.Context) {
+		s := Default(c)
+		flashes = s.Flashes()
+		s.Save()
+	})
+
+	request := func(path, cookie string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if cookie != "" {
+			req.Header.Set("Cookie", cookie)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	cookieOf := func(w *httptest.ResponseRecorder) string {
+		return strings.SplitN(w.Header().Get("Set-Cookie"), ";", 2)[0]
+	}
+
+	cookie := cookieOf(request("/add", ""))
+	cookie = cookieOf(request("/read", cookie))
+	if len(flashes) != 2 || flashes[0] != "hello" || flashes[1] != "world" {
+		t.Fatalf("Flashes() = %v, want [hello world]", flashes)
+	}
+	// 读取后保存的会话里不再有flash
+	request("/read", cookie)
+	if len(flashes) != 0 {
+		t.Errorf("Flashes() after consumed = %v, want none", flashes)
+	}
+}
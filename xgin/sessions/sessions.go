@@ -0,0 +1,297 @@
+// Package sessions provides cookie based sessions for xgin.
+//
+// The session values are gob encoded into a cookie signed with HMAC-SHA256
+// and optionally encrypted with AES-GCM, or kept in a Store with only the
+// signed session ID in the cookie. Types stored in a session other than the
+// basic ones must be registered with gob.Register.
+//
+//	r.Use(sessions.Sessions(sessions.Config{
+//		Keys: []sessions.KeyPair{{HashKey: hashKey, BlockKey: blockKey}},
+//	}))
+//	r.GET("/", func(c *xgin.Context) {
+//		s := sessions.Default(c)
+//		s.Set("count", s.GetInt("count")+1)
+//		s.Save()
+//	})
+package sessions
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/gob"
+	"errors"
+	"net/http"
+	"time"
+
+	"srcrd/xgin"
+)
+
+// DefaultKey is the Context key of the session.
+const DefaultKey = "srcrd/xgin/sessions"
+
+const flashesKey = "_flash"
+
+// maxCookieSize 浏览器通常限制单个cookie 4096字节(含name和属性)
+const maxCookieSize = 4000
+
+// ErrCookieTooLong is returned by Save when the encoded session doesn't fit
+// in a cookie, use a Store for larger sessions.
+var ErrCookieTooLong = errors.New("sessions: the encoded value is too long for a cookie")
+
+func init() {
+	// flash消息保存为[]interface{}
+	gob.Register([]interface{}{})
+}
+
+// Options are the attributes of the session cookie.
+type Options struct {
+	Path   string
+	Domain string
+	// MaxAge=0 means no Max-Age attribute, the session lasts for the browser session.
+	// MaxAge<0 means delete the session now.
+	// MaxAge>0 means Max-Age attribute present and given in seconds, older
+	// cookies are rejected even if the browser sends them.
+	MaxAge   int
+	Secure   bool
+	HttpOnly bool
+	SameSite http.SameSite
+}
+
+// Config defines the config for the Sessions middleware.
+type Config struct {
+	// Name of the cookie, default "session".
+	Name string
+
+	// Keys sign and encrypt the cookie. The first pair is used to encode,
+	// all of them are tried to decode: prepend a new pair to rotate the keys,
+	// drop the old one once its cookies have expired.
+	Keys []KeyPair
+
+	// Options of the cookie, Path defaults to "/".
+	Options Options
+
+	// Store, if set, keeps the values on the server side.
+	Store Store
+}
+
+// Sessions returns a middleware that makes the session available through
+// Default. The session is loaded on first use, call Session.Save before the
+// response is written to send the cookie.
+func Sessions(conf Config) xgin.HandlerFunc {
+	if conf.Name == "" {
+		conf.Name = "session"
+	}
+	if conf.Options.Path == "" {
+		conf.Options.Path = "/"
+	}
+	cd := newCodec(conf.Keys)
+
+	return func(c *xgin.Context) {
+		c.Set(DefaultKey, &Session{
+			c:       c,
+			conf:    &conf,
+			codec:   cd,
+			options: conf.Options,
+		})
+		c.Next()
+	}
+}
+
+// Default returns the session of the request, the Sessions middleware must
+// be in the handlers chain. The session writes its cookie through c, so
+// handlers running on a copy of the Context, ie. behind Timeout, must call
+// Default with the copy rather than keep the session of the original.
+func Default(c *xgin.Context) *Session {
+	s := c.MustGet(DefaultKey).(*Session)
+	// Timeout超时后原Context会被放回池中复用, 绑定到调用者的Context
+	s.c = c
+	return s
+}
+
+// Session holds the values of one request's session.
+type Session struct {
+	c       *xgin.Context
+	conf    *Config
+	codec   *codec
+	options Options
+
+	loaded bool
+	id     string
+	values map[string]interface{}
+}
+
+// ID returns the session ID when a Store is used, empty otherwise or before
+// the first Save of a new session.
+func (s *Session) ID() string {
+	s.load()
+	return s.id
+}
+
+// Get returns the session value associated to the given key.
+func (s *Session) Get(key string) interface{} {
+	s.load()
+	return s.values[key]
+}
+
+// GetString returns the value associated to the key as a string.
+func (s *Session) GetString(key string) string {
+	v, _ := s.Get(key).(string)
+	return v
+}
+
+// GetInt returns the value associated to the key as an int.
+func (s *Session) GetInt(key string) int {
+	v, _ := s.Get(key).(int)
+	return v
+}
+
+// Set sets the session value associated to the given key.
+func (s *Session) Set(key string, val interface{}) {
+	s.load()
+	s.values[key] = val
+}
+
+// Delete removes the session value associated to the given key.
+func (s *Session) Delete(key string) {
+	s.load()
+	delete(s.values, key)
+}
+
+// Clear deletes all values in the session.
+func (s *Session) Clear() {
+	s.load()
+	s.values = make(map[string]interface{})
+}
+
+// AddFlash adds a flash message to the session.
+// A single variadic argument is accepted, and it is optional: it defines the flash key.
+// If not defined "_flash" is used by default.
+func (s *Session) AddFlash(value interface{}, vars ...string) {
+	key := flashesKey
+	if len(vars) > 0 {
+		key = vars[0]
+	}
+	s.load()
+	flashes, _ := s.values[key].([]interface{})
+	s.values[key] = append(flashes, value)
+}
+
+// Flashes returns a slice of flash messages from the session and removes
+// them, Save must be called for the removal to persist.
+// A single variadic argument is accepted, and it is optional: it defines the flash key.
+// If not defined "_flash" is used by default.
+func (s *Session) Flashes(vars ...string) []interface{} {
+	key := flashesKey
+	if len(vars) > 0 {
+		key = vars[0]
+	}
+	s.load()
+	flashes, _ := s.values[key].([]interface{})
+	delete(s.values, key)
+	return flashes
+}
+
+// Options sets configuration for the session cookie of this request.
+func (s *Session) Options(options Options) {
+	if options.Path == "" {
+		options.Path = "/"
+	}
+	s.options = options
+}
+
+// Save writes the session cookie, and the values to the Store if any.
+// It must be called before the response body is written.
+func (s *Session) Save() error {
+	s.load()
+	opts := s.options
+
+	if opts.MaxAge < 0 {
+		if s.conf.Store != nil && s.id != "" {
+			if err := s.conf.Store.Delete(s.id); err != nil {
+				return err
+			}
+		}
+		s.id = ""
+		s.values = make(map[string]interface{})
+		s.setCookie("", opts)
+		return nil
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(s.values); err != nil {
+		return err
+	}
+	data := buf.Bytes()
+
+	if s.conf.Store != nil {
+		if s.id == "" {
+			id, err := newSessionID()
+			if err != nil {
+				return err
+			}
+			s.id = id
+		}
+		ttl := time.Duration(opts.MaxAge) * time.Second
+		if err := s.conf.Store.Save(s.id, data, ttl); err != nil {
+			return err
+		}
+		data = []byte(s.id)
+	}
+
+	value, err := s.codec.encode(s.conf.Name, data)
+	if err != nil {
+		return err
+	}
+	if len(s.conf.Name)+len(value) > maxCookieSize {
+		return ErrCookieTooLong
+	}
+	s.setCookie(value, opts)
+	return nil
+}
+
+func (s *Session) setCookie(value string, opts Options) {
+	s.c.SetSameSite(opts.SameSite)
+	s.c.SetCookie(s.conf.Name, value, opts.MaxAge, opts.Path, opts.Domain, opts.Secure, opts.HttpOnly)
+}
+
+// load 第一次访问时解析cookie, 无效或过期的cookie当作新session
+func (s *Session) load() {
+	if s.loaded {
+		return
+	}
+	s.loaded = true
+	s.values = make(map[string]interface{})
+
+	cookie, err := s.c.Cookie(s.conf.Name)
+	if err != nil || cookie == "" {
+		return
+	}
+	data, err := s.codec.decode(s.conf.Name, cookie, s.options.MaxAge)
+	if err != nil {
+		return
+	}
+	if s.conf.Store != nil {
+		id := string(data)
+		if data, err = s.conf.Store.Load(id); err != nil || data == nil {
+			if err != nil {
+				s.c.Error(err)
+			}
+			return
+		}
+		s.id = id
+	}
+
+	values := make(map[string]interface{})
+	if gob.NewDecoder(bytes.NewReader(data)).Decode(&values) == nil {
+		s.values = values
+	}
+}
+
+func newSessionID() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,67 @@
+package sessions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"srcrd/xgin"
+)
+
+var testKeys = []KeyPair{{HashKey: []byte("0123456789abcdef0123456789abcdef")}}
+
+func TestSessionsBehindTimeout(t *testing.T) {
+	r := xgin.New()
+	r.Use(Sessions(Config{Keys: testKeys}))
+
+	var late sync.WaitGroup
+	late.Add(1)
+	r.GET("/slow", xgin.Timeout(10*time.Millisecond), func(c *xgin.Context) {
+		defer late.Done()
+		time.Sleep(50 * time.Millisecond)
+		// 超时后原Context已被其他请求复用, 会话只能写到副本上
+		s := Default(c)
+		s.Set("late", true)
+		s.Save()
+	})
+	r.GET("/fast", func(c *xgin.Context) {
+		s := Default(c)
+		s.Set("n", s.GetInt("n")+1)
+		if err := s.Save(); err != nil {
+			t.Errorf("Save: %v", err)
+		}
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/slow", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("GET /slow code = %d, want 503", w.Code)
+	}
+
+	// 在迟到的handler保存会话的同时复用池中的Context
+	done := make(chan struct{})
+	go func() {
+		late.Wait()
+		close(done)
+	}()
+loop:
+	for {
+		select {
+		case <-done:
+			break loop
+		default:
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/fast", nil))
+		if w.Code != http.StatusOK || w.Header().Get("Set-Cookie") == "" {
+			t.Fatalf("GET /fast = %d, Set-Cookie %q", w.Code, w.Header().Get("Set-Cookie"))
+		}
+	}
+
+	// 超时响应已经发出, 迟到的Save不能再改它的header
+	if cookie := w.Header().Get("Set-Cookie"); cookie != "" {
+		t.Errorf("late Save wrote Set-Cookie %q into the timed out response", cookie)
+	}
+}
@@ -0,0 +1,97 @@
+package sessions
+
+import (
+	"sync"
+	"time"
+)
+
+// Store keeps the session values on the server side, the cookie then only
+// carries the signed session ID. data is the encoded session, ttl is the
+// session MaxAge, 0 if the session lasts for the browser session.
+type Store interface {
+	// Load returns the session data, nil if the session does not exist.
+	Load(id string) (data []byte, err error)
+	Save(id string, data []byte, ttl time.Duration) error
+	Delete(id string) error
+}
+
+// MemoryStore is a Store keeping the sessions in memory, they are lost on
+// restart and not shared between processes. The zero value is ready to use.
+type MemoryStore struct {
+	// SessionTTL bounds the sessions without MaxAge. Default 24h.
+	SessionTTL time.Duration
+
+	mu       sync.Mutex
+	sessions map[string]memorySession
+	ops      int
+}
+
+type memorySession struct {
+	data    []byte
+	expires time.Time
+}
+
+// defaultSessionTTL is the MemoryStore.SessionTTL default.
+const defaultSessionTTL = 24 * time.Hour
+
+var _ Store = &MemoryStore{}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		SessionTTL: defaultSessionTTL,
+		sessions:   make(map[string]memorySession),
+	}
+}
+
+// Load implements the Store interface.
+func (s *MemoryStore) Load(id string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess, ok := s.sessions[id]
+	if !ok {
+		return nil, nil
+	}
+	if time.Now().After(sess.expires) {
+		delete(s.sessions, id)
+		return nil, nil
+	}
+	return sess.data, nil
+}
+
+// Save implements the Store interface.
+func (s *MemoryStore) Save(id string, data []byte, ttl time.Duration) error {
+	if ttl <= 0 {
+		ttl = s.SessionTTL
+	}
+	if ttl <= 0 {
+		ttl = defaultSessionTTL
+	}
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.sessions == nil {
+		s.sessions = make(map[string]memorySession)
+	}
+	s.sessions[id] = memorySession{data: data, expires: now.Add(ttl)}
+
+	// 每1024次保存清理一次过期的session
+	s.ops++
+	if s.ops&1023 == 0 {
+		for id, sess := range s.sessions {
+			if now.After(sess.expires) {
+				delete(s.sessions, id)
+			}
+		}
+	}
+	return nil
+}
+
+// Delete implements the Store interface.
+func (s *MemoryStore) Delete(id string) error {
+	s.mu.Lock()
+	delete(s.sessions, id)
+	s.mu.Unlock()
+	return nil
+}
@@ -0,0 +1,188 @@
+package xgin
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// TimeoutConfig defines the config for the Timeout middleware.
+type TimeoutConfig struct {
+	// Timeout is the deadline of the remaining handlers chain.
+	Timeout time.Duration
+
+	// StatusCode is sent on timeout. Defaults to 503 Service Unavailable.
+	StatusCode int
+
+	// ContentType and Body are the response sent on timeout.
+	ContentType string
+	Body        string
+}
+
+// Timeout returns a middleware that answers 503 if the remaining handlers
+// do not finish within timeout.
+func Timeout(timeout time.Duration) HandlerFunc {
+	return TimeoutWithConfig(TimeoutConfig{Timeout: timeout})
+}
+
+// TimeoutWithConfig returns a Timeout middleware with config.
+//
+// The remaining chain runs in its own goroutine on a copy of the Context whose
+// Writer buffers the response and whose Request carries the deadline. If the
+// chain finishes in time the buffered response is written out, otherwise the
+// timeout response is sent and whatever the chain writes afterwards is dropped.
+// The copy is never returned to the engine's pool, so a handler still running
+// after the timeout can't touch a Context that serves another request.
+func TimeoutWithConfig(conf TimeoutConfig) HandlerFunc {
+	if conf.Timeout <= 0 {
+		panic("timeout must be positive")
+	}
+	if conf.StatusCode == 0 {
+		conf.StatusCode = http.StatusServiceUnavailable
+	}
+	if conf.ContentType == "" {
+		conf.ContentType = "text/plain; charset=utf-8"
+	}
+	if conf.Body == "" {
+		conf.Body = http.StatusText(conf.StatusCode)
+	}
+
+	return func(c *Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), conf.Timeout)
+		defer cancel()
+
+		tw := &timeoutWriter{h: make(http.Header), status: defaultStatus, size: noWritten}
+		cp := c.Copy()
+		cp.Writer = tw
+		cp.Request = c.Request.WithContext(ctx)
+		cp.handlers = c.handlers
+		cp.index = c.index
+
+		done := make(chan struct{})
+		panicChan := make(chan interface{}, 1)
+		go func() {
+			defer func() {
+				if p := recover(); p != nil {
+					panicChan <- p
+				}
+			}()
+			cp.Next()
+			close(done)
+		}()
+
+		select {
+		case p := <-panicChan:
+			panic(p)
+		case <-done:
+			tw.mu.Lock()
+			defer tw.mu.Unlock()
+			dst := c.Writer.Header()
+			for k, vv := range tw.h {
+				dst[k] = vv
+			}
+			c.Writer.WriteHeader(tw.status)
+			if tw.size != noWritten {
+				c.Writer.Write(tw.buf.Bytes())
+			}
+			c.mu.Lock()
+			c.Keys = cp.Keys
+			c.mu.Unlock()
+			c.Errors = append(c.Errors, cp.Errors...)
+			// 剩余的handlers已经在cp上执行过了
+			c.index = cp.index
+		case <-ctx.Done():
+			tw.mu.Lock()
+			tw.timedOut = true
+			tw.mu.Unlock()
+			c.Header("Content-Type", conf.ContentType)
+			c.Status(conf.StatusCode)
+			c.Writer.WriteString(conf.Body)
+			c.Abort()
+		}
+	}
+}
+
+// timeoutWriter buffers the response of the handlers run by Timeout.
+type timeoutWriter struct {
+	mu       sync.Mutex
+	h        http.Header
+	buf      bytes.Buffer
+	status   int
+	size     int
+	timedOut bool
+}
+
+var _ ResponseWriter = &timeoutWriter{}
+
+func (w *timeoutWriter) Header() http.Header {
+	return w.h
+}
+
+func (w *timeoutWriter) WriteHeader(code int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if code > 0 && !w.timedOut && w.size == noWritten {
+		w.status = code
+	}
+}
+
+func (w *timeoutWriter) WriteHeaderNow() {
+	w.mu.Lock()
+	if w.size == noWritten {
+		w.size = 0
+	}
+	w.mu.Unlock()
+}
+
+func (w *timeoutWriter) Write(data []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	if w.size == noWritten {
+		w.size = 0
+	}
+	n, err := w.buf.Write(data)
+	w.size += n
+	return n, err
+}
+
+func (w *timeoutWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *timeoutWriter) Status() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.status
+}
+
+func (w *timeoutWriter) Size() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.size
+}
+
+func (w *timeoutWriter) Written() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.size != noWritten
+}
+
+// Hijack is not supported, the connection may already carry the timeout response.
+func (w *timeoutWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return nil, nil, errors.New("xgin: Hijack is not supported behind Timeout")
+}
+
+// Flush is a no-op, the response is sent once the handlers return.
+func (w *timeoutWriter) Flush() {}
+
+func (w *timeoutWriter) Pusher() http.Pusher {
+	return nil
+}
@@ -0,0 +1,79 @@
+package xgin
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestTimeoutPassThrough(t *testing.T) {
+	r := New()
+	var key interface{}
+	var errs int
+	r.Use(func(c *Context) {
+		c.Next()
+		key, _ = c.Get("user")
+		errs = len(c.Errors)
+	})
+	r.GET("/", Timeout(time.Second), func(c *Context) {
+		c.Set("user", "gopher")
+		c.Error(errors.New("handler error"))
+		c.Header("X-Handler", "1")
+		c.Status(http.StatusCreated)
+		c.String("created")
+	})
+
+	w := performRequest(r, http.MethodGet, "/")
+	if w.Code != http.StatusCreated || w.Body.String() != "created" || w.Header().Get("X-Handler") != "1" {
+		t.Errorf("GET / = %d %q X-Handler=%q, want 201 %q", w.Code, w.Body.String(), w.Header().Get("X-Handler"), "created")
+	}
+	// 副本上设置的Keys和Errors要合并回原Context
+	if key != "gopher" || errs != 1 {
+		t.Errorf("Keys[user] = %v, len(Errors) = %d, want gopher, 1", key, errs)
+	}
+}
+
+func TestTimeoutExpired(t *testing.T) {
+	r := New()
+	release := make(chan struct{})
+	lateErr := make(chan error, 1)
+	r.GET("/", TimeoutWithConfig(TimeoutConfig{Timeout: 10 * time.Millisecond, Body: "too slow"}), func(c *Context) {
+		// 等超时响应发出后再写
+		<-release
+		c.Header("X-Late", "1")
+		c.Status(http.StatusOK)
+		_, err := c.Writer.Write([]byte("late"))
+		lateErr <- err
+	})
+
+	w := performRequest(r, http.MethodGet, "/")
+	close(release)
+	if w.Code != http.StatusServiceUnavailable || w.Body.String() != "too slow" {
+		t.Errorf("GET / = %d %q, want 503 %q", w.Code, w.Body.String(), "too slow")
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "text/plain; charset=utf-8" {
+		t.Errorf("Content-Type = %q, want text/plain; charset=utf-8", ct)
+	}
+	if err := <-lateErr; err != http.ErrHandlerTimeout {
+		t.Errorf("late Write error = %v, want http.ErrHandlerTimeout", err)
+	}
+	if w.Header().Get("X-Late") != "" || w.Body.String() != "too slow" {
+		t.Errorf("late handler changed the response: X-Late=%q body=%q", w.Header().Get("X-Late"), w.Body.String())
+	}
+}
+
+func TestTimeoutPanic(t *testing.T) {
+	r := New()
+	r.GET("/", Timeout(time.Second), func(c *Context) {
+		panic("boom")
+	})
+
+	// panic要传回到处理请求的goroutine, 由外层的Recovery处理
+	recv := catchPanic(func() {
+		performRequest(r, http.MethodGet, "/")
+	})
+	if recv != "boom" {
+		t.Errorf("recovered %v, want boom", recv)
+	}
+}
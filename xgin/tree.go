@@ -1,5 +1,10 @@
 package xgin
 
+import (
+	"net/url"
+	"strings"
+)
+
 // Param is a single URL parameter, consisting of a key and a value.
 type Param struct {
 	Key   string
@@ -11,6 +16,24 @@ type Param struct {
 // It is therefore safe to read values by the index.
 type Params []Param
 
+// Get returns the value of the first Param which key matches the given name.
+// If no matching Param is found, an empty string is returned.
+func (ps Params) Get(name string) (string, bool) {
+	for _, entry := range ps {
+		if entry.Key == name {
+			return entry.Value, true
+		}
+	}
+	return "", false
+}
+
+// ByName returns the value of the first Param which key matches the given name.
+// If no matching Param is found, an empty string is returned.
+func (ps Params) ByName(name string) (va string) {
+	va, _ = ps.Get(name)
+	return
+}
+
 type methodTree struct {
 	method string
 	root   *node
@@ -27,32 +50,543 @@ func (trees methodTrees) get(method string) *node {
 	return nil
 }
 
+func min(a, b int) int {
+	if a <= b {
+		return a
+	}
+	return b
+}
+
+func longestCommonPrefix(a, b string) int {
+	i := 0
+	max := min(len(a), len(b))
+	for i < max && a[i] == b[i] {
+		i++
+	}
+	return i
+}
+
+// countParams 统计路径中':'和'*'的个数, 用于预分配Params
+func countParams(path string) uint16 {
+	var n uint16
+	for i := 0; i < len(path); i++ {
+		switch path[i] {
+		case ':', '*':
+			n++
+		}
+	}
+	return n
+}
+
+type nodeType uint8
+
+const (
+	static nodeType = iota // default
+	root
+	param
+	catchAll
+)
+
+// 压缩前缀树(radix tree)，每个HTTP方法一棵
+//
+// /search/
+// /support
+// /blog/:post/
+// /about-us/team
+//
+// Priority   Path             Handle
+// 4          \
+// 2          |├s
+// 1          ||├earch\        *<1>
+// 1          ||└upport\       *<2>
+// 1          |├blog\          *<3>
+// 1          |  └:post        nil
+// 1          |    └\          *<3>
+// 1          └about-us\team   *<4>
 type node struct {
 	path      string
-	indices   string
-	wildChild bool
-	// nType     nodeType
-	priority uint32
-	children []*node // child nodes, at most 1 :param style node at the end of the array
-	handlers HandlersChain
-	fullPath string
+	indices   string // 子节点path的首字母, 与children一一对应
+	wildChild bool   // 唯一的子节点是否是 :param 或 *catchAll
+	nType     nodeType
+	priority  uint32  // 经过该节点的路由数, 子节点按priority排序, 热门路径先匹配
+	children  []*node // child nodes, a wildcard child is always the only child
+	handlers  HandlersChain
+	fullPath  string
+
+	// param节点的名字(不含':')和值约束, 如 :id<int> 的key为id
+	key        string
+	constraint paramConstraint
+}
+
+// Increments priority of the given child and reorders if necessary
+func (n *node) incrementChildPrio(pos int) int {
+	cs := n.children
+	cs[pos].priority++
+	prio := cs[pos].priority
+
+	// Adjust position (move to front)
+	newPos := pos
+	for ; newPos > 0 && cs[newPos-1].priority < prio; newPos-- {
+		// Swap node positions
+		cs[newPos-1], cs[newPos] = cs[newPos], cs[newPos-1]
+	}
+
+	// Build new index char string
+	if newPos != pos {
+		n.indices = n.indices[:newPos] + // Unchanged prefix, might be empty
+			n.indices[pos:pos+1] + // The index char we move
+			n.indices[newPos:pos] + n.indices[pos+1:] // Rest without char at 'pos'
+	}
+
+	return newPos
 }
 
+// addRoute adds a node with the given handle to the path.
+// Not concurrency-safe!
 func (n *node) addRoute(path string, handlers HandlersChain) {
-	// 沿根节点添加子阶段
-	n.fullPath = path
-	n.handlers = handlers
+	fullPath := path
+	n.priority++
+
+	// Empty tree
+	if len(n.path) == 0 && len(n.children) == 0 {
+		n.insertChild(path, fullPath, handlers)
+		n.nType = root
+		return
+	}
+
+	parentFullPathIndex := 0
+
+walk:
+	for {
+		// Find the longest common prefix.
+		// This also implies that the common prefix contains no ':' or '*'
+		// since the existing key can't contain those chars.
+		i := longestCommonPrefix(path, n.path)
+
+		// Split edge
+		// 公共前缀比当前节点短, 把当前节点拆成 公共前缀 + 剩余部分两个节点
+		if i < len(n.path) {
+			child := node{
+				path:      n.path[i:],
+				wildChild: n.wildChild,
+				indices:   n.indices,
+				children:  n.children,
+				handlers:  n.handlers,
+				priority:  n.priority - 1,
+				fullPath:  n.fullPath,
+			}
+
+			n.children = []*node{&child}
+			n.indices = string([]byte{n.path[i]})
+			n.path = path[:i]
+			n.handlers = nil
+			n.wildChild = false
+			n.fullPath = fullPath[:parentFullPathIndex+i]
+		}
+
+		// Make new node a child of this node
+		if i < len(path) {
+			path = path[i:]
+
+			if n.wildChild {
+				parentFullPathIndex += len(n.path)
+				n = n.children[0]
+				n.priority++
+
+				// Check if the wildcard matches
+				if len(path) >= len(n.path) && n.path == path[:len(n.path)] &&
+					// Adding a child to a catchAll is not possible
+					n.nType != catchAll &&
+					// Check for longer wildcard, e.g. :name and :names
+					(len(n.path) >= len(path) || path[len(n.path)] == '/') {
+					continue walk
+				}
+
+				// 同一个catch-all注册两次
+				if n.nType == catchAll && path == n.path && n.handlers != nil {
+					panic("handlers are already registered for path '" + fullPath +
+						"', existing path '" + n.fullPath + "'")
+				}
+
+				pathSeg := path
+				if n.nType != catchAll {
+					pathSeg = strings.SplitN(path, "/", 2)[0]
+				}
+				prefix := fullPath[:strings.Index(fullPath, pathSeg)] + n.path
+				panic("'" + pathSeg +
+					"' in new path '" + fullPath +
+					"' conflicts with existing wildcard '" + n.path +
+					"' in existing prefix '" + prefix +
+					"'")
+			}
+
+			c := path[0]
+
+			// slash after param
+			if n.nType == param && c == '/' && len(n.children) == 1 {
+				parentFullPathIndex += len(n.path)
+				n = n.children[0]
+				n.priority++
+				continue walk
+			}
+
+			// Check if a child with the next path byte exists
+			for i, max := 0, len(n.indices); i < max; i++ {
+				if c == n.indices[i] {
+					parentFullPathIndex += len(n.path)
+					i = n.incrementChildPrio(i)
+					n = n.children[i]
+					continue walk
+				}
+			}
+
+			// Otherwise insert it
+			if c != ':' && c != '*' {
+				n.indices += string([]byte{c})
+				child := &node{
+					fullPath: fullPath,
+				}
+				n.children = append(n.children, child)
+				n.incrementChildPrio(len(n.indices) - 1)
+				n = child
+			}
+			n.insertChild(path, fullPath, handlers)
+			return
+		}
+
+		// Otherwise add handle to current node
+		if n.handlers != nil {
+			panic("handlers are already registered for path '" + fullPath +
+				"', existing path '" + n.fullPath + "'")
+		}
+		n.handlers = handlers
+		n.fullPath = fullPath
+		return
+	}
 }
 
-func (n *node) getValue(path string, params *Params) (value nodeValue) {
-	// 从根结点向下查找
-	value = nodeValue{handlers: n.handlers, fullPath: n.fullPath}
-	return
+// Search for a wildcard segment and check the name for invalid characters.
+// Returns -1 as index, if no wildcard was found.
+func findWildcard(path string) (wildcard string, i int, valid bool) {
+	// Find start
+	for start, c := range []byte(path) {
+		// A wildcard starts with ':' (param) or '*' (catch-all)
+		if c != ':' && c != '*' {
+			continue
+		}
+
+		// Find end and check for invalid characters
+		// <>内是约束, 其中的'/', ':', '*'不算
+		valid = true
+		depth := 0
+		for end, c := range []byte(path[start+1:]) {
+			switch c {
+			case '<':
+				depth++
+			case '>':
+				if depth > 0 {
+					depth--
+				}
+			case '/':
+				if depth == 0 {
+					return path[start : start+1+end], start, valid
+				}
+			case ':', '*':
+				if depth == 0 {
+					valid = false
+				}
+			}
+		}
+		return path[start:], start, valid
+	}
+	return "", -1, false
+}
+
+func (n *node) insertChild(path string, fullPath string, handlers HandlersChain) {
+	for {
+		// Find prefix until first wildcard
+		wildcard, i, valid := findWildcard(path)
+		if i < 0 { // No wildcard found
+			break
+		}
+
+		// The wildcard name must not contain ':' and '*'
+		if !valid {
+			panic("only one wildcard per path segment is allowed, has: '" +
+				wildcard + "' in path '" + fullPath + "'")
+		}
+
+		name, constraint := splitWildcard(wildcard, fullPath)
+
+		// Check if the wildcard has a name
+		if len(name) < 2 {
+			panic("wildcards must be named with a non-empty name in path '" + fullPath + "'")
+		}
+
+		// Check if this node has existing children which would be
+		// unreachable if we insert the wildcard here
+		if len(n.children) > 0 {
+			panic("wildcard segment '" + wildcard +
+				"' in new path '" + fullPath +
+				"' conflicts with existing children in path '" + n.children[0].fullPath + "'")
+		}
+
+		if wildcard[0] == ':' { // param
+			if i > 0 {
+				// Insert prefix before the current wildcard
+				n.path = path[:i]
+				path = path[i:]
+			}
+
+			n.wildChild = true
+			child := &node{
+				nType:    param,
+				path:     wildcard,
+				fullPath: fullPath,
+				key:      name[1:],
+			}
+			if constraint != "" {
+				child.constraint = parseConstraint(constraint, fullPath)
+			}
+			n.children = []*node{child}
+			n = child
+			n.priority++
+
+			// if the path doesn't end with the wildcard, then there
+			// will be another non-wildcard subpath starting with '/'
+			if len(wildcard) < len(path) {
+				path = path[len(wildcard):]
+
+				child := &node{
+					priority: 1,
+					fullPath: fullPath,
+				}
+				n.children = []*node{child}
+				n = child
+				continue
+			}
+
+			// Otherwise we're done. Insert the handle in the new leaf
+			n.handlers = handlers
+			return
+		}
+
+		// catchAll
+		if constraint != "" {
+			panic("constraints are only allowed on :param wildcards in path '" + fullPath + "'")
+		}
+
+		if i+len(wildcard) != len(path) {
+			panic("catch-all routes are only allowed at the end of the path in path '" + fullPath + "'")
+		}
+
+		// /src/ 和 /src/*filepath 都能匹配 /src/, 不允许同时存在
+		if len(n.path) > 0 && n.path[len(n.path)-1] == '/' {
+			panic("catch-all '" + wildcard + "' in new path '" + fullPath +
+				"' conflicts with existing handle for the path segment root in path '" + n.fullPath + "'")
+		}
+
+		// currently fixed width 1 for '/'
+		i--
+		if i < 0 || path[i] != '/' {
+			panic("no / before catch-all in path '" + fullPath + "'")
+		}
+
+		n.path = path[:i]
+
+		// First node: catchAll node with empty path
+		child := &node{
+			wildChild: true,
+			nType:     catchAll,
+			fullPath:  fullPath,
+		}
+
+		n.children = []*node{child}
+		n.indices = string('/')
+		n = child
+		n.priority++
+
+		// second node: node holding the variable
+		child = &node{
+			path:     path[i:],
+			nType:    catchAll,
+			key:      name[1:],
+			handlers: handlers,
+			priority: 1,
+			fullPath: fullPath,
+		}
+		n.children = []*node{child}
+
+		return
+	}
+
+	// If no wildcard was found, simply insert the path and handle
+	n.path = path
+	n.handlers = handlers
+	n.fullPath = fullPath
 }
 
+// nodeValue holds return values of (*Node).getValue method
 type nodeValue struct {
 	handlers HandlersChain
 	params   *Params
 	tsr      bool
 	fullPath string
 }
+
+// Returns the handle registered with the given path (key). The values of
+// wildcards are saved to a map.
+// If no handle can be found, a TSR (trailing slash redirect) recommendation is
+// made if a handle exists with an extra (without the) trailing slash for the
+// given path.
+func (n *node) getValue(path string, params *Params, unescape bool) (value nodeValue) {
+walk: // Outer loop for walking the tree
+	for {
+		prefix := n.path
+		if len(path) > len(prefix) {
+			if path[:len(prefix)] == prefix {
+				path = path[len(prefix):]
+
+				// If this node does not have a wildcard (param or catchAll)
+				// child, we can just look up the next child node and continue
+				// to walk down the tree
+				if !n.wildChild {
+					idxc := path[0]
+					for i, c := range []byte(n.indices) {
+						if c == idxc {
+							n = n.children[i]
+							continue walk
+						}
+					}
+
+					// Nothing found.
+					// We can recommend to redirect to the same URL without a
+					// trailing slash if a leaf exists for that path.
+					value.tsr = path == "/" && n.handlers != nil
+					return
+				}
+
+				// Handle wildcard child
+				n = n.children[0]
+				switch n.nType {
+				case param:
+					// Find param end (either '/' or path end)
+					end := 0
+					for end < len(path) && path[end] != '/' {
+						end++
+					}
+
+					val := unescapeParam(path[:end], unescape)
+
+					// 值不满足约束, 当作没有匹配的路由
+					if n.constraint != nil && !n.constraint(val) {
+						return
+					}
+
+					// Save param value
+					if params != nil {
+						value.params = appendParam(value.params, params, n.key, val)
+					}
+
+					// We need to go deeper!
+					if end < len(path) {
+						if len(n.children) > 0 {
+							path = path[end:]
+							n = n.children[0]
+							continue walk
+						}
+
+						// ... but we can't
+						value.tsr = len(path) == end+1
+						return
+					}
+
+					if value.handlers = n.handlers; value.handlers != nil {
+						value.fullPath = n.fullPath
+						return
+					}
+					if len(n.children) == 1 {
+						// No handle found. Check if a handle for this path + a
+						// trailing slash exists for TSR recommendation
+						n = n.children[0]
+						value.tsr = n.path == "/" && n.handlers != nil
+					}
+					return
+
+				case catchAll:
+					// Save param value
+					if params != nil {
+						value.params = appendParam(value.params, params, n.key, unescapeParam(path, unescape))
+					}
+
+					value.handlers = n.handlers
+					value.fullPath = n.fullPath
+					return
+
+				default:
+					panic("invalid node type")
+				}
+			}
+		}
+
+		if path == prefix {
+			// We should have reached the node containing the handle.
+			// Check if this node has a handle registered.
+			if value.handlers = n.handlers; value.handlers != nil {
+				value.fullPath = n.fullPath
+				return
+			}
+
+			// If there is no handle for this route, but this route has a
+			// wildcard child, there must be a handle for this path with an
+			// additional trailing slash
+			if path == "/" && n.wildChild && n.nType != root {
+				value.tsr = true
+				return
+			}
+
+			// No handle found. Check if a handle for this path + a
+			// trailing slash exists for trailing slash recommendation
+			for i, c := range []byte(n.indices) {
+				if c == '/' {
+					n = n.children[i]
+					value.tsr = (len(n.path) == 1 && n.handlers != nil) ||
+						(n.nType == catchAll && n.children[0].handlers != nil)
+					return
+				}
+			}
+
+			return
+		}
+
+		// Nothing found. We can recommend to redirect to the same URL with an
+		// extra trailing slash if a leaf exists for that path
+		value.tsr = (path == "/") ||
+			(len(prefix) == len(path)+1 && prefix[len(path)] == '/' &&
+				path == prefix[:len(prefix)-1] && n.handlers != nil)
+		return
+	}
+}
+
+// appendParam 在预分配的容量内扩展params, 容量由engine.maxParams保证
+func appendParam(value, params *Params, key, val string) *Params {
+	if value == nil {
+		value = params
+	}
+	i := len(*value)
+	*value = (*value)[:i+1]
+	(*value)[i] = Param{
+		Key:   key,
+		Value: val,
+	}
+	return value
+}
+
+func unescapeParam(val string, unescape bool) string {
+	if unescape {
+		if v, err := url.QueryUnescape(val); err == nil {
+			return v
+		}
+	}
+	return val
+}
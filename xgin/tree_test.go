@@ -0,0 +1,401 @@
+package xgin
+
+import (
+	"reflect"
+	"testing"
+)
+
+// fakeHandler returns a chain whose only handler records its name in *got.
+func fakeHandler(got *string, name string) HandlersChain {
+	return HandlersChain{func(*Context) { *got = name }}
+}
+
+type testRequest struct {
+	path     string
+	nilHandl bool
+	route    string
+	params   Params
+}
+
+func checkRequests(t *testing.T, tree *node, got *string, requests []testRequest) {
+	t.Helper()
+	for _, request := range requests {
+		params := make(Params, 0, 10)
+		value := tree.getValue(request.path, &params, false)
+
+		if value.handlers == nil {
+			if !request.nilHandl {
+				t.Errorf("handle mismatch for route '%s': Expected non-nil handle", request.path)
+			}
+			continue
+		}
+		if request.nilHandl {
+			t.Errorf("handle mismatch for route '%s': Expected nil handle", request.path)
+			continue
+		}
+		value.handlers[0](nil)
+		if *got != request.route {
+			t.Errorf("handle mismatch for route '%s': Wrong handle (%s != %s)", request.path, *got, request.route)
+		}
+
+		var ps Params
+		if value.params != nil {
+			ps = *value.params
+		}
+		if len(ps) == 0 && len(request.params) == 0 {
+			continue
+		}
+		if !reflect.DeepEqual(ps, request.params) {
+			t.Errorf("Params mismatch for route '%s': %v != %v", request.path, ps, request.params)
+		}
+	}
+}
+
+func TestTreeWildcard(t *testing.T) {
+	tree := &node{}
+	var got string
+
+	routes := [...]string{
+		"/",
+		"/cmd/:tool/",
+		"/cmd/:tool/:sub",
+		"/src/*filepath",
+		"/search/",
+		"/search/:query",
+		"/user_:name",
+		"/user_:name/about",
+		"/files/:dir/*filepath",
+		"/doc/",
+		"/doc/go_faq.html",
+		"/info/:user/public",
+		"/info/:user/project/:project",
+	}
+	for _, route := range routes {
+		tree.addRoute(route, fakeHandler(&got, route))
+	}
+
+	checkRequests(t, tree, &got, []testRequest{
+		{"/", false, "/", nil},
+		{"/cmd/test/", false, "/cmd/:tool/", Params{Param{"tool", "test"}}},
+		{"/cmd/test", true, "", Params{Param{"tool", "test"}}},
+		{"/cmd/test/3", false, "/cmd/:tool/:sub", Params{Param{"tool", "test"}, Param{"sub", "3"}}},
+		{"/src/", false, "/src/*filepath", Params{Param{"filepath", "/"}}},
+		{"/src/some/file.png", false, "/src/*filepath", Params{Param{"filepath", "/some/file.png"}}},
+		{"/search/", false, "/search/", nil},
+		{"/search/someth!ng+in+ünìcodé", false, "/search/:query", Params{Param{"query", "someth!ng+in+ünìcodé"}}},
+		{"/search/someth!ng+in+ünìcodé/", true, "", Params{Param{"query", "someth!ng+in+ünìcodé"}}},
+		{"/user_gopher", false, "/user_:name", Params{Param{"name", "gopher"}}},
+		{"/user_gopher/about", false, "/user_:name/about", Params{Param{"name", "gopher"}}},
+		{"/files/js/inc/framework.js", false, "/files/:dir/*filepath", Params{Param{"dir", "js"}, Param{"filepath", "/inc/framework.js"}}},
+		{"/info/gordon/public", false, "/info/:user/public", Params{Param{"user", "gordon"}}},
+		{"/info/gordon/project/go", false, "/info/:user/project/:project", Params{Param{"user", "gordon"}, Param{"project", "go"}}},
+	})
+}
+
+func TestTreeTrailingSlashRedirect(t *testing.T) {
+	tree := &node{}
+	var got string
+
+	routes := [...]string{
+		"/hi",
+		"/b/",
+		"/search/:query",
+		"/cmd/:tool/",
+		"/src/*filepath",
+		"/x",
+		"/x/y",
+		"/y/",
+		"/y/z",
+		"/0/:id",
+		"/0/:id/1",
+		"/1/:id/",
+		"/1/:id/2",
+		"/aa",
+		"/a/",
+		"/admin",
+		"/admin/:category",
+		"/admin/:category/:page",
+		"/doc",
+		"/doc/go_faq.html",
+		"/doc/go1.html",
+		"/no/a",
+		"/no/b",
+		"/api/hello/:name",
+	}
+	for _, route := range routes {
+		tree.addRoute(route, fakeHandler(&got, route))
+	}
+
+	tsrRoutes := [...]string{
+		"/hi/",
+		"/b",
+		"/search/gopher/",
+		"/cmd/vet",
+		"/src",
+		"/x/",
+		"/y",
+		"/0/go/",
+		"/1/go",
+		"/a",
+		"/admin/",
+		"/admin/config/",
+		"/admin/config/permissions/",
+		"/doc/",
+	}
+	for _, route := range tsrRoutes {
+		value := tree.getValue(route, nil, false)
+		if value.handlers != nil {
+			t.Errorf("non-nil handler for TSR route '%s'", route)
+		} else if !value.tsr {
+			t.Errorf("expected TSR recommendation for route '%s'", route)
+		}
+	}
+
+	noTsrRoutes := [...]string{
+		"/",
+		"/no",
+		"/no/",
+		"/_",
+		"/_/",
+		"/api/world/abc",
+	}
+	for _, route := range noTsrRoutes {
+		value := tree.getValue(route, nil, false)
+		if value.handlers != nil {
+			t.Errorf("non-nil handler for No-TSR route '%s'", route)
+		} else if value.tsr {
+			t.Errorf("expected no TSR recommendation for route '%s'", route)
+		}
+	}
+}
+
+func TestTreeWildcardConflict(t *testing.T) {
+	routes := []struct {
+		path     string
+		conflict bool
+	}{
+		{"/cmd/:tool/:sub", false},
+		{"/cmd/vet", true},
+		{"/src/*filepath", false},
+		{"/src/*filepathx", true},
+		{"/src/", true},
+		{"/search/:query", false},
+		{"/search/:queryx", true},
+		{"/user_:name", false},
+		{"/user_:namex", true},
+		{"/id:id", false},
+		{"/id/:id", true},
+	}
+	tree := &node{}
+	var got string
+	for _, route := range routes {
+		recv := catchPanic(func() {
+			tree.addRoute(route.path, fakeHandler(&got, route.path))
+		})
+		if route.conflict && recv == nil {
+			t.Errorf("no panic for conflicting route '%s'", route.path)
+		} else if !route.conflict && recv != nil {
+			t.Errorf("unexpected panic for route '%s': %v", route.path, recv)
+		}
+	}
+}
+
+func TestTreeDuplicatePath(t *testing.T) {
+	tree := &node{}
+	var got string
+
+	routes := [...]string{
+		"/",
+		"/doc/",
+		"/src/*filepath",
+		"/search/:query",
+		"/user_:name",
+	}
+	for _, route := range routes {
+		recv := catchPanic(func() {
+			tree.addRoute(route, fakeHandler(&got, route))
+		})
+		if recv != nil {
+			t.Fatalf("panic inserting route '%s': %v", route, recv)
+		}
+
+		// 同一路径第二次注册要panic, 消息里有新旧两个路径
+		recv = catchPanic(func() {
+			tree.addRoute(route, nil)
+		})
+		want := "handlers are already registered for path '" + route + "', existing path '" + route + "'"
+		if recv != want {
+			t.Errorf("duplicate route '%s': panic %v, want %q", route, recv, want)
+		}
+	}
+
+	checkRequests(t, tree, &got, []testRequest{
+		{"/", false, "/", nil},
+		{"/doc/", false, "/doc/", nil},
+		{"/src/some/file.png", false, "/src/*filepath", Params{Param{"filepath", "/some/file.png"}}},
+		{"/search/someth!ng+in+ünìcodé", false, "/search/:query", Params{Param{"query", "someth!ng+in+ünìcodé"}}},
+		{"/user_gopher", false, "/user_:name", Params{Param{"name", "gopher"}}},
+	})
+}
+
+func TestTreeConflictMessages(t *testing.T) {
+	tests := []struct {
+		existing string
+		path     string
+		want     string
+	}{
+		{
+			"/users/:id", "/users/:name",
+			"':name' in new path '/users/:name' conflicts with existing wildcard ':id' in existing prefix '/users/:id'",
+		},
+		{
+			"/users/:id", "/users/new",
+			"'new' in new path '/users/new' conflicts with existing wildcard ':id' in existing prefix '/users/:id'",
+		},
+		{
+			"/src/", "/src/*filepath",
+			"catch-all '*filepath' in new path '/src/*filepath' conflicts with existing handle for the path segment root in path '/src/'",
+		},
+	}
+	for _, tt := range tests {
+		tree := &node{}
+		var got string
+		tree.addRoute(tt.existing, fakeHandler(&got, tt.existing))
+		recv := catchPanic(func() {
+			tree.addRoute(tt.path, fakeHandler(&got, tt.path))
+		})
+		if recv != tt.want {
+			t.Errorf("'%s' after '%s': panic %v, want %q", tt.path, tt.existing, recv, tt.want)
+		}
+	}
+}
+
+func TestTreeInvalidWildcards(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/user:", "wildcards must be named with a non-empty name in path '/user:'"},
+		{"/user:/", "wildcards must be named with a non-empty name in path '/user:/'"},
+		{"/cmd/:/", "wildcards must be named with a non-empty name in path '/cmd/:/'"},
+		{"/src/*", "wildcards must be named with a non-empty name in path '/src/*'"},
+		{"/src/*filepath/x", "catch-all routes are only allowed at the end of the path in path '/src/*filepath/x'"},
+		{"/cmd/:tool:sub", "only one wildcard per path segment is allowed, has: ':tool:sub' in path '/cmd/:tool:sub'"},
+	}
+	for _, tt := range tests {
+		tree := &node{}
+		recv := catchPanic(func() {
+			tree.addRoute(tt.path, nil)
+		})
+		if recv != tt.want {
+			t.Errorf("route '%s': panic %v, want %q", tt.path, recv, tt.want)
+		}
+	}
+}
+
+func catchPanic(testFunc func()) (recv interface{}) {
+	defer func() {
+		recv = recover()
+	}()
+	testFunc()
+	return
+}
+
+func TestTreeConstraints(t *testing.T) {
+	tree := &node{}
+	var got string
+
+	routes := [...]string{
+		"/users/:id<int>",
+		"/users/:id<int>/posts",
+		"/orders/:id<uint>",
+		"/items/:id<uuid>",
+		"/tags/:name<alpha>",
+		"/codes/:code<re:[a-z]{2}-\\d+>",
+		"/times/:t<re:\\d{2}:\\d{2}>",
+		"/slash/:p<re:a/b|ab>",
+		"/named/:n<re:(?P<num>\\d+)>/x",
+	}
+	for _, route := range routes {
+		tree.addRoute(route, fakeHandler(&got, route))
+	}
+
+	checkRequests(t, tree, &got, []testRequest{
+		{"/users/42", false, "/users/:id<int>", Params{Param{"id", "42"}}},
+		{"/users/-7", false, "/users/:id<int>", Params{Param{"id", "-7"}}},
+		{"/users/42/posts", false, "/users/:id<int>/posts", Params{Param{"id", "42"}}},
+		{"/users/abc", true, "", nil},
+		{"/users/abc/posts", true, "", nil},
+		{"/orders/7", false, "/orders/:id<uint>", Params{Param{"id", "7"}}},
+		{"/orders/-7", true, "", nil},
+		{"/items/123e4567-e89b-12d3-a456-426614174000", false, "/items/:id<uuid>", Params{Param{"id", "123e4567-e89b-12d3-a456-426614174000"}}},
+		{"/items/123e4567e89b12d3a456426614174000", true, "", nil},
+		{"/items/123e4567-e89b-12d3-a456-42661417400g", true, "", nil},
+		{"/tags/Go", false, "/tags/:name<alpha>", Params{Param{"name", "Go"}}},
+		{"/tags/go1", true, "", nil},
+		{"/codes/ab-12", false, "/codes/:code<re:[a-z]{2}-\\d+>", Params{Param{"code", "ab-12"}}},
+		{"/codes/ab-12x", true, "", nil},
+		{"/times/10:30", false, "/times/:t<re:\\d{2}:\\d{2}>", Params{Param{"t", "10:30"}}},
+		{"/times/1030", true, "", nil},
+		{"/slash/ab", false, "/slash/:p<re:a/b|ab>", Params{Param{"p", "ab"}}},
+		{"/slash/a/b", true, "", nil},
+		{"/named/42/x", false, "/named/:n<re:(?P<num>\\d+)>/x", Params{Param{"n", "42"}}},
+		{"/named/x/x", true, "", nil},
+	})
+
+	// 不满足约束时没有TSR, 不会重定向到另一个同样不匹配的路径
+	noTsrRoutes := [...]string{
+		"/users/abc/",
+		"/users/abc/posts/",
+		"/orders/-7/",
+		"/tags/go1/",
+	}
+	for _, route := range noTsrRoutes {
+		value := tree.getValue(route, nil, false)
+		if value.handlers != nil {
+			t.Errorf("non-nil handler for route '%s'", route)
+		} else if value.tsr {
+			t.Errorf("expected no TSR recommendation for route '%s'", route)
+		}
+	}
+}
+
+func TestTreeConstraintPanics(t *testing.T) {
+	tests := []struct {
+		routes []string
+		want   string
+	}{
+		{
+			[]string{"/users/:id<int>", "/users/:id<uint>"},
+			"':id<uint>' in new path '/users/:id<uint>' conflicts with existing wildcard ':id<int>' in existing prefix '/users/:id<int>'",
+		},
+		{
+			[]string{"/users/:id<float>"},
+			"unknown constraint 'float' in path '/users/:id<float>'",
+		},
+		{
+			[]string{"/users/:id<int"},
+			"unterminated constraint in wildcard ':id<int' in path '/users/:id<int'",
+		},
+		{
+			[]string{"/users/:id<re:[>"},
+			"invalid constraint 're:[' in path '/users/:id<re:[>': error parsing regexp: missing closing ]: `[)$`",
+		},
+		{
+			[]string{"/src/*path<int>"},
+			"constraints are only allowed on :param wildcards in path '/src/*path<int>'",
+		},
+	}
+	for _, tt := range tests {
+		tree := &node{}
+		var got string
+		recv := catchPanic(func() {
+			for _, route := range tt.routes {
+				tree.addRoute(route, fakeHandler(&got, route))
+			}
+		})
+		if recv != tt.want {
+			t.Errorf("%v: panic %v, want %q", tt.routes, recv, tt.want)
+		}
+	}
+}
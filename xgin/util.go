@@ -1,6 +1,30 @@
 package xgin
 
-import "path"
+import (
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"reflect"
+	"runtime"
+)
+
+// H is a shortcut for map[string]interface{}
+type H map[string]interface{}
+
+// WrapF is a helper function for wrapping http.HandlerFunc and returns a Gin middleware.
+func WrapF(f http.HandlerFunc) HandlerFunc {
+	return func(c *Context) {
+		f(c.Writer, c.Request)
+	}
+}
+
+// WrapH is a helper function for wrapping http.Handler and returns a Gin middleware.
+func WrapH(h http.Handler) HandlerFunc {
+	return func(c *Context) {
+		h.ServeHTTP(c.Writer, c.Request)
+	}
+}
 
 func joinPaths(absolutePath, relativePath string) string {
 	if relativePath == "" {
@@ -20,3 +44,29 @@ func lastChar(str string) uint8 {
 	}
 	return str[len(str)-1]
 }
+
+func assert1(guard bool, text string) {
+	if !guard {
+		panic(text)
+	}
+}
+
+func resolveAddress(addr []string) string {
+	switch len(addr) {
+	case 0:
+		if port := os.Getenv("PORT"); port != "" {
+			log.Printf("Environment variable PORT=\"%s\"", port)
+			return ":" + port
+		}
+		log.Printf("Environment variable PORT is undefined. Using port :8080 by default")
+		return ":8080"
+	case 1:
+		return addr[0]
+	default:
+		panic("too many parameters")
+	}
+}
+
+func nameOfFunction(f interface{}) string {
+	return runtime.FuncForPC(reflect.ValueOf(f).Pointer()).Name()
+}
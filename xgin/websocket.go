@@ -0,0 +1,35 @@
+package xgin
+
+import (
+	"srcrd/xgin/websocket"
+)
+
+// Upgrade upgrades the request to the WebSocket protocol with u. On failure
+// the error response is already written, the context is aborted and the
+// error is added to c.Errors.
+//
+//	var upgrader = websocket.Upgrader{Subprotocols: []string{"chat"}}
+//
+//	r.GET("/ws", func(c *xgin.Context) {
+//		conn, err := c.Upgrade(&upgrader)
+//		if err != nil {
+//			return
+//		}
+//		defer conn.Close()
+//		for {
+//			mt, msg, err := conn.ReadMessage()
+//			if err != nil {
+//				return
+//			}
+//			conn.WriteMessage(mt, msg)
+//		}
+//	})
+func (c *Context) Upgrade(u *websocket.Upgrader) (*websocket.Conn, error) {
+	conn, err := u.Upgrade(c.Writer, c.Request)
+	if err != nil {
+		c.Abort()
+		c.Error(err)
+		return nil, err
+	}
+	return conn, nil
+}
@@ -0,0 +1,499 @@
+// Package websocket implements the WebSocket protocol defined in RFC 6455.
+// Use xgin.Context.Upgrade to upgrade a request, NewConn wraps any net.Conn,
+// ie. one end of a net.Pipe.
+package websocket
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// The message types are defined in RFC 6455, section 11.8.
+const (
+	// TextMessage denotes a text data message. The text message payload is
+	// interpreted as UTF-8 encoded text data.
+	TextMessage = 1
+
+	// BinaryMessage denotes a binary data message.
+	BinaryMessage = 2
+
+	// CloseMessage denotes a close control message. The optional message
+	// payload contains a numeric code and text. Use the FormatCloseMessage
+	// function to format a close message payload.
+	CloseMessage = 8
+
+	// PingMessage denotes a ping control message. The optional message payload
+	// is UTF-8 encoded text.
+	PingMessage = 9
+
+	// PongMessage denotes a pong control message. The optional message payload
+	// is UTF-8 encoded text.
+	PongMessage = 10
+
+	continuationFrame = 0
+)
+
+// Close codes defined in RFC 6455, section 11.7.
+const (
+	CloseNormalClosure           = 1000
+	CloseGoingAway               = 1001
+	CloseProtocolError           = 1002
+	CloseUnsupportedData         = 1003
+	CloseNoStatusReceived        = 1005
+	CloseAbnormalClosure         = 1006
+	CloseInvalidFramePayloadData = 1007
+	ClosePolicyViolation         = 1008
+	CloseMessageTooBig           = 1009
+	CloseInternalServerErr       = 1011
+)
+
+const (
+	finalBit = 1 << 7
+	rsvBits  = 7 << 4
+	maskBit  = 1 << 7
+
+	maxControlFramePayloadSize = 125
+
+	// DefaultReadLimit is the maximum message size read by default.
+	DefaultReadLimit = 32 << 20
+
+	closeTimeout = 5 * time.Second
+)
+
+var (
+	// ErrReadLimit is returned when reading a message that is larger than the
+	// read limit set for the connection.
+	ErrReadLimit = errors.New("websocket: read limit exceeded")
+
+	// ErrCloseSent is returned when the application writes a message to the
+	// connection after sending a close message.
+	ErrCloseSent = errors.New("websocket: close sent")
+)
+
+// CloseError is returned by ReadMessage when the peer closed the connection.
+type CloseError struct {
+	Code int
+	Text string
+}
+
+func (e *CloseError) Error() string {
+	return "websocket: close " + strconv.Itoa(e.Code) + " " + e.Text
+}
+
+// protocolError is a violation of RFC 6455 by the peer, the connection is
+// closed with code.
+type protocolError struct {
+	code int
+	msg  string
+}
+
+func (e *protocolError) Error() string {
+	return "websocket: " + e.msg
+}
+
+// FormatCloseMessage formats closeCode and text as a WebSocket close message.
+func FormatCloseMessage(closeCode int, text string) []byte {
+	if closeCode == CloseNoStatusReceived {
+		// Return empty message because it's illegal to send
+		// CloseNoStatusReceived. Return non-nil value in case application
+		// checks for nil.
+		return []byte{}
+	}
+	buf := make([]byte, 2+len(text))
+	binary.BigEndian.PutUint16(buf, uint16(closeCode))
+	copy(buf[2:], text)
+	return buf
+}
+
+// Conn represents a WebSocket connection.
+//
+// Applications are responsible for ensuring that no more than one goroutine
+// calls the read methods concurrently. The write methods are safe to call
+// concurrently, control frames are written by the reader while it handles
+// ping and close messages.
+type Conn struct {
+	conn        net.Conn
+	br          *bufio.Reader
+	isServer    bool
+	subprotocol string
+
+	readLimit int64
+	readErr   error
+
+	// 写锁, 保证一个frame完整写出
+	wmu       sync.Mutex
+	closeSent bool
+
+	handlePing func(appData string) error
+	handlePong func(appData string) error
+}
+
+// NewConn returns a WebSocket connection over conn, the handshake must
+// already be done. isServer selects the masking rules: clients mask their
+// frames, servers do not. br may carry data buffered during the handshake,
+// if nil a new reader is created.
+func NewConn(conn net.Conn, br *bufio.Reader, isServer bool) *Conn {
+	if br == nil {
+		br = bufio.NewReader(conn)
+	}
+	c := &Conn{
+		conn:      conn,
+		br:        br,
+		isServer:  isServer,
+		readLimit: DefaultReadLimit,
+	}
+	c.handlePing = func(appData string) error {
+		return c.WriteControl(PongMessage, []byte(appData))
+	}
+	c.handlePong = func(string) error { return nil }
+	return c
+}
+
+// Subprotocol returns the negotiated protocol for the connection.
+func (c *Conn) Subprotocol() string {
+	return c.subprotocol
+}
+
+// UnderlyingConn returns the internal net.Conn.
+func (c *Conn) UnderlyingConn() net.Conn {
+	return c.conn
+}
+
+// SetReadLimit sets the maximum size in bytes for a message read from the peer. If a
+// message exceeds the limit, the connection sends a close message to the peer
+// and returns ErrReadLimit to the application. A limit <= 0 restores
+// DefaultReadLimit, the payload is allocated from the frame length so reads
+// can't be unlimited.
+func (c *Conn) SetReadLimit(limit int64) {
+	if limit <= 0 {
+		limit = DefaultReadLimit
+	}
+	c.readLimit = limit
+}
+
+// SetPingHandler sets the handler for ping messages received from the peer.
+// The default ping handler sends a pong to the peer.
+func (c *Conn) SetPingHandler(h func(appData string) error) {
+	c.handlePing = h
+}
+
+// SetPongHandler sets the handler for pong messages received from the peer.
+// The default pong handler does nothing.
+func (c *Conn) SetPongHandler(h func(appData string) error) {
+	c.handlePong = h
+}
+
+// SetReadDeadline sets the read deadline on the underlying network connection.
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	return c.conn.SetReadDeadline(t)
+}
+
+// SetWriteDeadline sets the write deadline on the underlying network connection.
+func (c *Conn) SetWriteDeadline(t time.Time) error {
+	return c.conn.SetWriteDeadline(t)
+}
+
+// Close closes the underlying network connection without sending or waiting
+// for a close message.
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
+
+// CloseHandshake sends a close message with code and text, waits up to
+// 5 seconds for the peer's close message, then closes the connection.
+// Messages received in the meantime are discarded.
+func (c *Conn) CloseHandshake(code int, text string) error {
+	err := c.WriteControl(CloseMessage, FormatCloseMessage(code, text))
+	if err == nil && c.readErr == nil {
+		c.conn.SetReadDeadline(time.Now().Add(closeTimeout))
+		for {
+			if _, _, err := c.ReadMessage(); err != nil {
+				break
+			}
+		}
+	}
+	return c.conn.Close()
+}
+
+// WriteMessage writes data as a single frame message.
+func (c *Conn) WriteMessage(messageType int, data []byte) error {
+	if messageType != TextMessage && messageType != BinaryMessage {
+		return c.WriteControl(messageType, data)
+	}
+	return c.WriteFragments(messageType, data)
+}
+
+// WriteFragments writes one message split into a frame per fragment,
+// the first frame carries messageType, the others are continuation frames.
+func (c *Conn) WriteFragments(messageType int, fragments ...[]byte) error {
+	if messageType != TextMessage && messageType != BinaryMessage {
+		return errors.New("websocket: bad data message type " + strconv.Itoa(messageType))
+	}
+	if len(fragments) == 0 {
+		fragments = [][]byte{nil}
+	}
+
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	if c.closeSent {
+		return ErrCloseSent
+	}
+	for i, p := range fragments {
+		opcode := messageType
+		if i > 0 {
+			opcode = continuationFrame
+		}
+		if err := c.writeFrame(opcode, i == len(fragments)-1, p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteControl writes a control message (close, ping or pong).
+// The payload of a control message must not exceed 125 bytes.
+func (c *Conn) WriteControl(messageType int, data []byte) error {
+	if messageType != CloseMessage && messageType != PingMessage && messageType != PongMessage {
+		return errors.New("websocket: bad control message type " + strconv.Itoa(messageType))
+	}
+	if len(data) > maxControlFramePayloadSize {
+		return errors.New("websocket: invalid control frame")
+	}
+
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	if c.closeSent {
+		return ErrCloseSent
+	}
+	if messageType == CloseMessage {
+		c.closeSent = true
+	}
+	return c.writeFrame(messageType, true, data)
+}
+
+// writeFrame 写一个frame, 调用者持有c.wmu
+func (c *Conn) writeFrame(opcode int, final bool, payload []byte) error {
+	header := make([]byte, 0, 14)
+	b0 := byte(opcode)
+	if final {
+		b0 |= finalBit
+	}
+	header = append(header, b0)
+
+	var b1 byte
+	if !c.isServer {
+		b1 = maskBit
+	}
+	n := len(payload)
+	switch {
+	case n <= 125:
+		header = append(header, b1|byte(n))
+	case n <= 65535:
+		header = append(header, b1|126, byte(n>>8), byte(n))
+	default:
+		header = append(header, b1|127)
+		header = append(header, make([]byte, 8)...)
+		binary.BigEndian.PutUint64(header[len(header)-8:], uint64(n))
+	}
+
+	// 客户端发送的frame必须mask, 拷贝一份再mask, 不修改调用者的数据
+	if !c.isServer {
+		var key [4]byte
+		if _, err := rand.Read(key[:]); err != nil {
+			return err
+		}
+		header = append(header, key[:]...)
+		masked := make([]byte, n)
+		copy(masked, payload)
+		maskBytes(key, masked)
+		payload = masked
+	}
+
+	buf := make([]byte, 0, len(header)+n)
+	buf = append(buf, header...)
+	buf = append(buf, payload...)
+	_, err := c.conn.Write(buf)
+	return err
+}
+
+// ReadMessage reads the next data message, fragments are joined.
+// Control frames are handled while reading: pings are answered by the ping
+// handler, pongs go to the pong handler, a close message is echoed and
+// returned as a *CloseError. Once an error is returned, all subsequent
+// reads return the same error.
+func (c *Conn) ReadMessage() (messageType int, p []byte, err error) {
+	if c.readErr != nil {
+		return 0, nil, c.readErr
+	}
+	messageType, p, err = c.readMessage()
+	if err != nil {
+		c.readErr = err
+		if pe, ok := err.(*protocolError); ok {
+			c.WriteControl(CloseMessage, FormatCloseMessage(pe.code, pe.msg))
+		} else if err == ErrReadLimit {
+			c.WriteControl(CloseMessage, FormatCloseMessage(CloseMessageTooBig, ""))
+		}
+	}
+	return messageType, p, err
+}
+
+func (c *Conn) readMessage() (int, []byte, error) {
+	messageType := 0
+	var message []byte
+	for {
+		opcode, final, payload, err := c.readFrame()
+		if err != nil {
+			return 0, nil, err
+		}
+
+		switch opcode {
+		case PingMessage:
+			if err := c.handlePing(string(payload)); err != nil && err != ErrCloseSent {
+				return 0, nil, err
+			}
+			continue
+		case PongMessage:
+			if err := c.handlePong(string(payload)); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case CloseMessage:
+			return 0, nil, c.handleClose(payload)
+		case continuationFrame:
+			if messageType == 0 {
+				return 0, nil, &protocolError{CloseProtocolError, "continuation frame without a started message"}
+			}
+		case TextMessage, BinaryMessage:
+			if messageType != 0 {
+				return 0, nil, &protocolError{CloseProtocolError, "data frame inside a fragmented message"}
+			}
+			messageType = opcode
+		default:
+			return 0, nil, &protocolError{CloseProtocolError, "unknown opcode " + strconv.Itoa(opcode)}
+		}
+
+		if int64(len(message))+int64(len(payload)) > c.readLimit {
+			return 0, nil, ErrReadLimit
+		}
+		message = append(message, payload...)
+		if final {
+			break
+		}
+	}
+
+	if messageType == TextMessage && !utf8.Valid(message) {
+		return 0, nil, &protocolError{CloseInvalidFramePayloadData, "invalid UTF-8 in text message"}
+	}
+	if message == nil {
+		message = []byte{}
+	}
+	return messageType, message, nil
+}
+
+// handleClose 回应对端的close frame, 返回*CloseError
+func (c *Conn) handleClose(payload []byte) error {
+	closeErr := &CloseError{Code: CloseNoStatusReceived}
+	switch {
+	case len(payload) == 1:
+		return &protocolError{CloseProtocolError, "invalid close payload"}
+	case len(payload) >= 2:
+		closeErr.Code = int(binary.BigEndian.Uint16(payload))
+		closeErr.Text = string(payload[2:])
+		if !validReceivedCloseCode(closeErr.Code) {
+			return &protocolError{CloseProtocolError, "invalid close code"}
+		}
+		if !utf8.ValidString(closeErr.Text) {
+			return &protocolError{CloseInvalidFramePayloadData, "invalid UTF-8 in close frame"}
+		}
+	}
+	// 已经发送过close的一方(主动关闭方)不再回应
+	c.WriteControl(CloseMessage, FormatCloseMessage(closeErr.Code, ""))
+	return closeErr
+}
+
+// readFrame reads one frame and unmasks its payload.
+func (c *Conn) readFrame() (opcode int, final bool, payload []byte, err error) {
+	var h [2]byte
+	if _, err = io.ReadFull(c.br, h[:]); err != nil {
+		return 0, false, nil, err
+	}
+
+	final = h[0]&finalBit != 0
+	opcode = int(h[0] & 0xf)
+	if h[0]&rsvBits != 0 {
+		return 0, false, nil, &protocolError{CloseProtocolError, "unexpected reserved bits"}
+	}
+
+	masked := h[1]&maskBit != 0
+	if masked != c.isServer {
+		return 0, false, nil, &protocolError{CloseProtocolError, "bad MASK"}
+	}
+
+	n := int64(h[1] & 0x7f)
+	switch n {
+	case 126:
+		var b [2]byte
+		if _, err = io.ReadFull(c.br, b[:]); err != nil {
+			return 0, false, nil, err
+		}
+		n = int64(binary.BigEndian.Uint16(b[:]))
+	case 127:
+		var b [8]byte
+		if _, err = io.ReadFull(c.br, b[:]); err != nil {
+			return 0, false, nil, err
+		}
+		n = int64(binary.BigEndian.Uint64(b[:]))
+		if n < 0 {
+			return 0, false, nil, &protocolError{CloseProtocolError, "invalid payload length"}
+		}
+	}
+
+	if opcode >= CloseMessage {
+		if n > maxControlFramePayloadSize || !final {
+			return 0, false, nil, &protocolError{CloseProtocolError, "invalid control frame"}
+		}
+	} else if n > c.readLimit {
+		return 0, false, nil, ErrReadLimit
+	}
+
+	var key [4]byte
+	if masked {
+		if _, err = io.ReadFull(c.br, key[:]); err != nil {
+			return 0, false, nil, err
+		}
+	}
+
+	payload = make([]byte, n)
+	if _, err = io.ReadFull(c.br, payload); err != nil {
+		return 0, false, nil, err
+	}
+	if masked {
+		maskBytes(key, payload)
+	}
+	return opcode, final, payload, nil
+}
+
+func maskBytes(key [4]byte, b []byte) {
+	for i := range b {
+		b[i] ^= key[i&3]
+	}
+}
+
+// validReceivedCloseCode 见RFC 6455 7.4.1, 1005/1006/1015不能出现在close frame中
+func validReceivedCloseCode(code int) bool {
+	switch {
+	case code >= 1000 && code <= 1003, code >= 1007 && code <= 1011:
+		return true
+	case code >= 3000 && code <= 4999:
+		return true
+	}
+	return false
+}
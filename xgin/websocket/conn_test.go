@@ -0,0 +1,372 @@
+package websocket
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// newPipe returns a server and a client Conn over a net.Pipe, and the client
+// end of the pipe so tests can write raw frames to the server.
+func newPipe(t *testing.T) (server *Conn, client *Conn, clientRaw net.Conn) {
+	t.Helper()
+	s, c := net.Pipe()
+	t.Cleanup(func() {
+		s.Close()
+		c.Close()
+	})
+	deadline := time.Now().Add(5 * time.Second)
+	s.SetDeadline(deadline)
+	c.SetDeadline(deadline)
+	return NewConn(s, nil, true), NewConn(c, nil, false), c
+}
+
+// rawFrame encodes a frame, masked with a fixed key if masked.
+func rawFrame(opcode int, final, masked bool, payload []byte) []byte {
+	b0 := byte(opcode)
+	if final {
+		b0 |= finalBit
+	}
+	b := []byte{b0}
+	var b1 byte
+	if masked {
+		b1 = maskBit
+	}
+	switch n := len(payload); {
+	case n <= 125:
+		b = append(b, b1|byte(n))
+	case n <= 65535:
+		b = append(b, b1|126, byte(n>>8), byte(n))
+	default:
+		b = append(b, b1|127, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(b[len(b)-8:], uint64(n))
+	}
+	p := append([]byte(nil), payload...)
+	if masked {
+		key := [4]byte{1, 2, 3, 4}
+		b = append(b, key[:]...)
+		maskBytes(key, p)
+	}
+	return append(b, p...)
+}
+
+// writeAsync writes to a net.Pipe end without blocking the test, the pipe
+// is unbuffered.
+func writeAsync(w io.Writer, frames ...[]byte) <-chan error {
+	errc := make(chan error, 1)
+	go func() {
+		for _, f := range frames {
+			if _, err := w.Write(f); err != nil {
+				errc <- err
+				return
+			}
+		}
+		errc <- nil
+	}()
+	return errc
+}
+
+func TestClientFramesAreMasked(t *testing.T) {
+	s, c := net.Pipe()
+	defer s.Close()
+	defer c.Close()
+	client := NewConn(c, nil, false)
+
+	payload := []byte("hello")
+	errc := make(chan error, 1)
+	go func() { errc <- client.WriteMessage(TextMessage, payload) }()
+
+	var h [2]byte
+	if _, err := io.ReadFull(s, h[:]); err != nil {
+		t.Fatal(err)
+	}
+	if h[0] != finalBit|TextMessage {
+		t.Errorf("first byte = %#x, want %#x", h[0], finalBit|TextMessage)
+	}
+	if h[1]&maskBit == 0 {
+		t.Fatal("client frame is not masked")
+	}
+	if n := int(h[1] &^ maskBit); n != len(payload) {
+		t.Fatalf("payload length = %d, want %d", n, len(payload))
+	}
+	var key [4]byte
+	got := make([]byte, len(payload))
+	if _, err := io.ReadFull(s, key[:]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.ReadFull(s, got); err != nil {
+		t.Fatal(err)
+	}
+	maskBytes(key, got)
+	if !bytes.Equal(got, payload) {
+		t.Errorf("unmasked payload = %q, want %q", got, payload)
+	}
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+	if string(payload) != "hello" {
+		t.Errorf("WriteMessage modified the caller's data: %q", payload)
+	}
+}
+
+func TestServerFramesAreNotMasked(t *testing.T) {
+	server, client, _ := newPipe(t)
+	errc := writeAsync(writerFunc(func(p []byte) (int, error) {
+		return len(p), server.WriteMessage(BinaryMessage, p)
+	}), []byte{0, 1, 2})
+
+	typ, p, err := client.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if typ != BinaryMessage || !bytes.Equal(p, []byte{0, 1, 2}) {
+		t.Errorf("ReadMessage = %d %v, want %d [0 1 2]", typ, p, BinaryMessage)
+	}
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestServerRejectsUnmaskedFrame(t *testing.T) {
+	server, _, clientRaw := newPipe(t)
+	writeAsync(clientRaw, rawFrame(TextMessage, true, false, []byte("hi")))
+	// 服务端回应的close frame需要有人读
+	go io.Copy(io.Discard, clientRaw)
+
+	_, _, err := server.ReadMessage()
+	if _, ok := err.(*protocolError); !ok {
+		t.Fatalf("ReadMessage error = %v, want a protocol error", err)
+	}
+}
+
+func TestFragmentedMessage(t *testing.T) {
+	tests := []struct {
+		name      string
+		fragments [][]byte
+		want      string
+	}{
+		{"single", [][]byte{[]byte("hello")}, "hello"},
+		{"fragments", [][]byte{[]byte("he"), []byte("l"), []byte("lo")}, "hello"},
+		{"empty fragments", [][]byte{nil, []byte("hello"), nil}, "hello"},
+		{"large", [][]byte{bytes.Repeat([]byte("a"), 70000), []byte("b")}, string(bytes.Repeat([]byte("a"), 70000)) + "b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, client, _ := newPipe(t)
+			errc := make(chan error, 1)
+			go func() { errc <- client.WriteFragments(TextMessage, tt.fragments...) }()
+
+			typ, p, err := server.ReadMessage()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if typ != TextMessage || string(p) != tt.want {
+				t.Errorf("ReadMessage = %d %.20q (len %d), want %d %.20q", typ, p, len(p), TextMessage, tt.want)
+			}
+			if err := <-errc; err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestControlFramesInterleavedWithFragments(t *testing.T) {
+	server, client, clientRaw := newPipe(t)
+	pongs := make(chan string, 1)
+	client.SetPongHandler(func(appData string) error {
+		pongs <- appData
+		return nil
+	})
+	serverPongs := make(chan string, 1)
+	server.SetPongHandler(func(appData string) error {
+		serverPongs <- appData
+		return nil
+	})
+
+	writeAsync(clientRaw,
+		rawFrame(TextMessage, false, true, []byte("hel")),
+		rawFrame(PingMessage, true, true, []byte("ping")),
+		rawFrame(continuationFrame, false, true, []byte("l")),
+		rawFrame(PongMessage, true, true, []byte("unsolicited")),
+		rawFrame(continuationFrame, true, true, []byte("o")),
+	)
+	// 服务端在读消息的过程中回应pong, 客户端要同时读
+	go client.ReadMessage()
+
+	typ, p, err := server.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if typ != TextMessage || string(p) != "hello" {
+		t.Errorf("ReadMessage = %d %q, want %d %q", typ, p, TextMessage, "hello")
+	}
+	if got := <-pongs; got != "ping" {
+		t.Errorf("pong payload = %q, want %q", got, "ping")
+	}
+	if got := <-serverPongs; got != "unsolicited" {
+		t.Errorf("server pong handler got %q, want %q", got, "unsolicited")
+	}
+}
+
+func TestInvalidFrameSequences(t *testing.T) {
+	tests := []struct {
+		name   string
+		frames [][]byte
+		code   int
+	}{
+		{"continuation without start", [][]byte{
+			rawFrame(continuationFrame, true, true, []byte("x")),
+		}, CloseProtocolError},
+		{"data frame inside fragments", [][]byte{
+			rawFrame(TextMessage, false, true, []byte("a")),
+			rawFrame(TextMessage, true, true, []byte("b")),
+		}, CloseProtocolError},
+		{"fragmented control frame", [][]byte{
+			rawFrame(PingMessage, false, true, []byte("a")),
+		}, CloseProtocolError},
+		{"control frame too long", [][]byte{
+			rawFrame(PingMessage, true, true, bytes.Repeat([]byte("a"), 126)),
+		}, CloseProtocolError},
+		{"invalid UTF-8", [][]byte{
+			rawFrame(TextMessage, true, true, []byte{0xff, 0xfe}),
+		}, CloseInvalidFramePayloadData},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, client, clientRaw := newPipe(t)
+			writeAsync(clientRaw, tt.frames...)
+			closed := make(chan error, 1)
+			go func() {
+				_, _, err := client.ReadMessage()
+				closed <- err
+			}()
+
+			if _, _, err := server.ReadMessage(); err == nil {
+				t.Fatal("ReadMessage succeeded, want an error")
+			}
+			drain(server)
+			var ce *CloseError
+			if err := <-closed; !errors.As(err, &ce) || ce.Code != tt.code {
+				t.Errorf("client got %v, want close code %d", err, tt.code)
+			}
+		})
+	}
+}
+
+func TestReadLimit(t *testing.T) {
+	tests := []struct {
+		name      string
+		fragments [][]byte
+	}{
+		{"single frame", [][]byte{bytes.Repeat([]byte("a"), 11)}},
+		{"fragments", [][]byte{bytes.Repeat([]byte("a"), 6), bytes.Repeat([]byte("a"), 6)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, client, _ := newPipe(t)
+			server.SetReadLimit(10)
+			go client.WriteFragments(BinaryMessage, tt.fragments...)
+			closed := make(chan error, 1)
+			go func() {
+				_, _, err := client.ReadMessage()
+				closed <- err
+			}()
+
+			if _, _, err := server.ReadMessage(); err != ErrReadLimit {
+				t.Fatalf("ReadMessage error = %v, want ErrReadLimit", err)
+			}
+			if _, _, err := server.ReadMessage(); err != ErrReadLimit {
+				t.Errorf("second ReadMessage error = %v, want ErrReadLimit", err)
+			}
+			drain(server)
+			var ce *CloseError
+			if err := <-closed; !errors.As(err, &ce) || ce.Code != CloseMessageTooBig {
+				t.Errorf("client got %v, want close code %d", err, CloseMessageTooBig)
+			}
+		})
+	}
+}
+
+func TestReadLimitExactSize(t *testing.T) {
+	server, client, _ := newPipe(t)
+	server.SetReadLimit(10)
+	go client.WriteFragments(BinaryMessage, []byte("12345"), []byte("67890"))
+
+	if _, p, err := server.ReadMessage(); err != nil || string(p) != "1234567890" {
+		t.Fatalf("ReadMessage = %q %v, want %q", p, err, "1234567890")
+	}
+}
+
+func TestReadLimitNotDisabled(t *testing.T) {
+	for _, limit := range []int64{0, -1} {
+		server, _, clientRaw := newPipe(t)
+		server.SetReadLimit(limit)
+		// 只发帧头, 声明一个巨大的长度, 不能按它分配内存
+		header := []byte{finalBit | BinaryMessage, maskBit | 127, 0, 0, 0, 0, 0, 0, 0, 0, 1, 2, 3, 4}
+		binary.BigEndian.PutUint64(header[2:10], 1<<62)
+		writeAsync(clientRaw, header)
+		go io.Copy(io.Discard, clientRaw)
+
+		if _, _, err := server.ReadMessage(); err != ErrReadLimit {
+			t.Errorf("SetReadLimit(%d): ReadMessage error = %v, want ErrReadLimit", limit, err)
+		}
+	}
+}
+
+func TestCloseHandshake(t *testing.T) {
+	server, client, _ := newPipe(t)
+	done := make(chan error, 1)
+	go func() { done <- client.CloseHandshake(CloseNormalClosure, "bye") }()
+
+	_, _, err := server.ReadMessage()
+	ce, ok := err.(*CloseError)
+	if !ok || ce.Code != CloseNormalClosure || ce.Text != "bye" {
+		t.Fatalf("ReadMessage error = %v, want close 1000 bye", err)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("CloseHandshake: %v", err)
+	}
+	// 错误会一直返回
+	if _, _, err2 := server.ReadMessage(); err2 != err {
+		t.Errorf("second ReadMessage error = %v, want %v", err2, err)
+	}
+	if err := server.WriteMessage(TextMessage, []byte("late")); err != ErrCloseSent {
+		t.Errorf("WriteMessage after close = %v, want ErrCloseSent", err)
+	}
+}
+
+func TestCloseWithoutStatus(t *testing.T) {
+	server, _, clientRaw := newPipe(t)
+	writeAsync(clientRaw, rawFrame(CloseMessage, true, true, nil))
+	echo := make(chan []byte, 1)
+	go func() {
+		b := make([]byte, 2)
+		io.ReadFull(clientRaw, b)
+		echo <- b
+	}()
+
+	_, _, err := server.ReadMessage()
+	if ce, ok := err.(*CloseError); !ok || ce.Code != CloseNoStatusReceived {
+		t.Fatalf("ReadMessage error = %v, want close %d", err, CloseNoStatusReceived)
+	}
+	// 1005不能发送, 回应空的close frame
+	if b := <-echo; b[0] != finalBit|CloseMessage || b[1] != 0 {
+		t.Errorf("echoed close frame header = %#v, want an empty close frame", b)
+	}
+}
+
+// drain discards what the peer still sends after c stopped reading, so its
+// writes to the unbuffered pipe don't block.
+func drain(c *Conn) {
+	go io.Copy(io.Discard, c.UnderlyingConn())
+}
+
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
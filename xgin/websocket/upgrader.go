@@ -0,0 +1,184 @@
+package websocket
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// 握手时与Sec-WebSocket-Key拼接的固定GUID, 见RFC 6455 1.3
+const keyGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// HandshakeError describes an error with the handshake from the peer.
+type HandshakeError struct {
+	message string
+}
+
+func (e HandshakeError) Error() string { return e.message }
+
+// Upgrader specifies parameters for upgrading an HTTP connection to a
+// WebSocket connection.
+type Upgrader struct {
+	// Subprotocols specifies the server's supported protocols in order of
+	// preference. The first one also requested by the client is selected.
+	Subprotocols []string
+
+	// ReadLimit is the maximum message size, DefaultReadLimit if <= 0.
+	ReadLimit int64
+
+	// CheckOrigin returns true if the request Origin header is acceptable. If
+	// CheckOrigin is nil, then a safe default is used: return false if the
+	// Origin request header is present and the origin host is not equal to
+	// request Host header.
+	CheckOrigin func(r *http.Request) bool
+}
+
+// Upgrade upgrades the HTTP server connection to the WebSocket protocol.
+//
+// If the upgrade fails, then Upgrade replies to the client with an HTTP error
+// response and returns a HandshakeError. w must implement http.Hijacker.
+func (u *Upgrader) Upgrade(w http.ResponseWriter, r *http.Request) (*Conn, error) {
+	if r.Method != http.MethodGet {
+		return u.returnError(w, http.StatusMethodNotAllowed, "websocket: the client is not using the websocket protocol: request method is not GET")
+	}
+	if !tokenListContainsValue(r.Header, "Connection", "upgrade") {
+		return u.returnError(w, http.StatusBadRequest, "websocket: the client is not using the websocket protocol: 'upgrade' token not found in 'Connection' header")
+	}
+	if !tokenListContainsValue(r.Header, "Upgrade", "websocket") {
+		return u.returnError(w, http.StatusBadRequest, "websocket: the client is not using the websocket protocol: 'websocket' token not found in 'Upgrade' header")
+	}
+	if r.Header.Get("Sec-Websocket-Version") != "13" {
+		w.Header().Set("Sec-Websocket-Version", "13")
+		return u.returnError(w, http.StatusUpgradeRequired, "websocket: unsupported version: 13 not found in 'Sec-Websocket-Version' header")
+	}
+
+	checkOrigin := u.CheckOrigin
+	if checkOrigin == nil {
+		checkOrigin = checkSameOrigin
+	}
+	if !checkOrigin(r) {
+		return u.returnError(w, http.StatusForbidden, "websocket: request origin not allowed by Upgrader.CheckOrigin")
+	}
+
+	challengeKey := r.Header.Get("Sec-Websocket-Key")
+	if !isValidChallengeKey(challengeKey) {
+		return u.returnError(w, http.StatusBadRequest, "websocket: not a websocket handshake: 'Sec-WebSocket-Key' header must be Base64 encoded value of 16-byte in length")
+	}
+
+	subprotocol := u.selectSubprotocol(r)
+
+	h, ok := w.(http.Hijacker)
+	if !ok {
+		return u.returnError(w, http.StatusInternalServerError, "websocket: response does not implement http.Hijacker")
+	}
+	netConn, brw, err := h.Hijack()
+	if err != nil {
+		return u.returnError(w, http.StatusInternalServerError, err.Error())
+	}
+	if brw.Reader.Buffered() > 0 && r.ContentLength > 0 {
+		netConn.Close()
+		return nil, errors.New("websocket: client sent data before handshake is complete")
+	}
+
+	p := "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: " +
+		computeAcceptKey(challengeKey) + "\r\n"
+	if subprotocol != "" {
+		p += "Sec-WebSocket-Protocol: " + subprotocol + "\r\n"
+	}
+	p += "\r\n"
+	if _, err = netConn.Write([]byte(p)); err != nil {
+		netConn.Close()
+		return nil, err
+	}
+
+	c := NewConn(netConn, brw.Reader, true)
+	c.subprotocol = subprotocol
+	if u.ReadLimit > 0 {
+		c.SetReadLimit(u.ReadLimit)
+	}
+	return c, nil
+}
+
+func (u *Upgrader) returnError(w http.ResponseWriter, status int, reason string) (*Conn, error) {
+	err := HandshakeError{reason}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write([]byte(http.StatusText(status)))
+	return nil, err
+}
+
+func (u *Upgrader) selectSubprotocol(r *http.Request) string {
+	clientProtocols := Subprotocols(r)
+	for _, serverProtocol := range u.Subprotocols {
+		for _, clientProtocol := range clientProtocols {
+			if clientProtocol == serverProtocol {
+				return clientProtocol
+			}
+		}
+	}
+	return ""
+}
+
+// Subprotocols returns the subprotocols requested by the client in the
+// Sec-Websocket-Protocol header.
+func Subprotocols(r *http.Request) []string {
+	var protocols []string
+	for _, h := range r.Header["Sec-Websocket-Protocol"] {
+		for _, p := range strings.Split(h, ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				protocols = append(protocols, p)
+			}
+		}
+	}
+	return protocols
+}
+
+// checkSameOrigin returns true if the origin is not set or is equal to the request host.
+func checkSameOrigin(r *http.Request) bool {
+	origin := r.Header["Origin"]
+	if len(origin) == 0 {
+		return true
+	}
+	u, err := url.Parse(origin[0])
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, r.Host)
+}
+
+// tokenListContainsValue returns true if the 1#token header with the given
+// name contains a token equal to value with ASCII case folding.
+func tokenListContainsValue(header http.Header, name string, value string) bool {
+	for _, h := range header[http.CanonicalHeaderKey(name)] {
+		for _, token := range strings.Split(h, ",") {
+			if strings.EqualFold(strings.TrimSpace(token), value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func computeAcceptKey(challengeKey string) string {
+	h := sha1.New()
+	h.Write([]byte(challengeKey))
+	h.Write([]byte(keyGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// isValidChallengeKey checks if the argument meets RFC6455 specification.
+func isValidChallengeKey(s string) bool {
+	// From RFC6455:
+	//
+	// A |Sec-WebSocket-Key| header field with a base64-encoded (see
+	// Section 4 of [RFC4648]) value that, when decoded, is 16 bytes in
+	// length.
+	if s == "" {
+		return false
+	}
+	decoded, err := base64.StdEncoding.DecodeString(s)
+	return err == nil && len(decoded) == 16
+}